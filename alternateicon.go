@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/missdeer/yairc/util"
+	"github.com/nfnt/resize"
+)
+
+var (
+	alternateIconsPlist = template.Must(template.New("AlternateIcons.plist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+{{- range $key := .Keys}}
+	<key>{{$key.Name}}</key>
+	<dict>
+		<key>CFBundleAlternateIcons</key>
+		<dict>
+{{- range $.Icons}}
+			<key>{{.Name}}</key>
+			<dict>
+{{- if $.Legacy}}
+				<key>CFBundleIconFiles</key>
+				<array>
+{{- $name := .Name}}
+{{- range $key.Files}}
+					<string>{{$name}}{{.}}</string>
+{{- end}}
+				</array>
+{{- else}}
+				<key>CFBundleIconName</key>
+				<string>{{.Name}}</string>
+{{- end}}
+				<key>UIPrerenderedIcon</key>
+				<true/>
+			</dict>
+{{- end}}
+		</dict>
+	</dict>
+{{- end}}
+</dict>
+</plist>
+`))
+)
+
+// the loose files of legacy alternate icons, iPhone picks the 60pt icon and iPad the 76pt and 83.5pt ones
+var legacyAlternateIconFiles = []struct {
+	Size   float64
+	Scales []int
+}{
+	{60, []int{2, 3}},
+	{76, []int{1, 2}},
+	{83.5, []int{2}},
+}
+
+type alternateIconsKey struct {
+	Name string
+	// file names without the owner name and scale suffix, only used by legacy alternate icons
+	Files []string
+}
+
+type alternateIcon struct {
+	Name  string
+	Image image.Image
+}
+
+// loadAlternateIcons decodes every image in dir, the file name without extension becomes the alternate icon name.
+func loadAlternateIcons(dir string) (icons []alternateIcon, err error) {
+	for _, fn := range expandImageArgs([]string{dir}) {
+		reader, err := util.OpenURI(fn)
+		if err != nil {
+			log.Println(fn, err)
+			return nil, err
+		}
+		m, _, err := util.ImageDecode(reader)
+		reader.Close()
+		if err != nil {
+			log.Println(fn, err)
+			return nil, err
+		}
+		if m, err = prepareAppIcon(m); err != nil {
+			log.Println(fn, err)
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(fn), filepath.Ext(fn))
+		if name == "AppIcon" {
+			return nil, fmt.Errorf("%s would overwrite the primary AppIcon set, rename it", fn)
+		}
+		icons = append(icons, alternateIcon{Name: name, Image: m})
+	}
+	sort.Slice(icons, func(i, j int) bool { return icons[i].Name < icons[j].Name })
+	return icons, nil
+}
+
+// GenerateAlternateAppIcons writes one appiconset per image in dir next to AppIcon.appiconset, single-size sets when single is set,
+// or loose 60pt, 76pt and 83.5pt files when legacy is set, and the CFBundleAlternateIcons entries to merge into Info.plist.
+func GenerateAlternateAppIcons(dir string, legacy bool, single bool) error {
+	icons, err := loadAlternateIcons(dir)
+	if err != nil {
+		return err
+	}
+	if len(icons) == 0 {
+		log.Println("no alternate icon found in", dir)
+		return nil
+	}
+
+	iosDir := path.Join(outputPath, "appicon", "ios")
+	for _, icon := range icons {
+		if legacy {
+			altDir := path.Join(iosDir, "AlternateIcons")
+			if err = os.MkdirAll(altDir, 0755); err != nil {
+				return err
+			}
+			for _, file := range legacyAlternateIconFiles {
+				for _, scale := range file.Scales {
+					length := uint(file.Size*float64(scale) + 0.5)
+					fn := path.Join(altDir, legacyAlternateIconFilename(icon.Name, file.Size, scale))
+					if err = saveAndCrush(resize.Resize(length, length, icon.Image, resize.Bilinear), fn); err != nil {
+						log.Println(fn, err)
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		log.Println("generating", icon.Name+".appiconset")
+		setDir := path.Join(iosDir, "Images.xcassets", icon.Name+".appiconset")
+		if single {
+			im := resize.Resize(modernAppIconLength, modernAppIconLength, icon.Image, resize.Bilinear)
+			if err = writeSingleSizeAppIconSet(setDir, []appIconVariant{{"", icon.Name + ".png", im}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = os.MkdirAll(setDir, 0755); err != nil {
+			return err
+		}
+		if err = writeAppIconSet(icon.Image, setDir, appIconSpecifications, map[string]interface{}{"pre-rendered": true}); err != nil {
+			return err
+		}
+	}
+
+	fd, err := os.OpenFile(path.Join(iosDir, "AlternateIcons.plist"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return alternateIconsPlist.Execute(fd, struct {
+		Keys   []alternateIconsKey
+		Icons  []alternateIcon
+		Legacy bool
+	}{
+		Keys: []alternateIconsKey{
+			{"CFBundleIcons", []string{legacyAlternateIconBasename(60)}},
+			{"CFBundleIcons~ipad", []string{legacyAlternateIconBasename(76), legacyAlternateIconBasename(83.5)}},
+		},
+		Icons:  icons,
+		Legacy: legacy,
+	})
+}
+
+func legacyAlternateIconBasename(size float64) string {
+	s := strconv.FormatFloat(size, 'f', -1, 64)
+	return s + "x" + s
+}
+
+// legacyAlternateIconFilename follows the <name><size>x<size>@<scale>x.png pattern iOS resolves CFBundleIconFiles with.
+func legacyAlternateIconFilename(name string, size float64, scale int) string {
+	if scale == 1 {
+		return name + legacyAlternateIconBasename(size) + ".png"
+	}
+	return fmt.Sprintf("%s%s@%dx.png", name, legacyAlternateIconBasename(size), scale)
+}
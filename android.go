@@ -1,43 +1,173 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
 	"log"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/missdeer/yairc/util"
 	"github.com/nfnt/resize"
 )
 
 type SplashScreenSpec struct {
-	Width  int
-	Height int
-	Name   string
+	Width   int
+	Height  int
+	Density string
 }
 
 type LauncherIconSpec struct {
-	Length int
+	Length  int
+	Density string
+}
+
+type FormFactorSpec struct {
 	Name   string
+	Round  bool
+	Banner bool
+}
+
+type AndroidDensity struct {
+	Name  string
+	Scale float64
+}
+
+func (d AndroidDensity) Pixels(dp int) int {
+	return int(float64(dp)*d.Scale + 0.5)
 }
 
 var (
 	SplashScreenSpecifications = []SplashScreenSpec{
-		{768, 1280, "drawable-xhdpi"},
-		{800, 1280, "drawable-213dpi"},
-		{1080, 1920, "drawable-xxhdpi"},
-		{1440, 2560, "drawable-560dpi"},
+		{240, 320, "ldpi"},
+		{320, 480, "mdpi"},
+		{480, 800, "hdpi"},
+		{720, 1280, "xhdpi"},
+		{1080, 1920, "xxhdpi"},
+		{1440, 2560, "xxxhdpi"},
+	}
+	AndroidDensities = []AndroidDensity{
+		{"ldpi", 0.75},
+		{"mdpi", 1},
+		{"hdpi", 1.5},
+		{"xhdpi", 2},
+		{"xxhdpi", 3},
+		{"xxxhdpi", 4},
+	}
+	FormFactorSpecifications = []FormFactorSpec{
+		{"phone", false, false},
+		{"tv", false, true},
+		// Wear OS and Android Auto show launcher icons in a circle
+		{"wear", true, false},
+		{"auto", true, false},
 	}
 	LauncherIconSpecifications = []LauncherIconSpec{
-		{36, "drawable-ldpi"},
-		{48, "drawable-mdpi"},
-		{64, "drawable-tvdpi"},
-		{72, "drawable-hdpi"},
-		{96, "drawable-xhdpi"},
-		{144, "drawable-xxhdpi"},
-		{192, "darwable-xxxhdpi"},
+		{36, "ldpi"},
+		{48, "mdpi"},
+		{64, "tvdpi"},
+		{72, "hdpi"},
+		{96, "xhdpi"},
+		{144, "xxhdpi"},
+		{192, "xxxhdpi"},
 	}
 )
 
-func GenerateSplashScreen(origin string) error {
-	return nil
+func GenerateSplashScreen() error {
+	bm, fm, err := loadBackgroundForeground()
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range SplashScreenSpecifications {
+		for _, orientation := range []string{"port", "land"} {
+			ls := launchImageSpec{Width: spec.Width, Height: spec.Height}
+			if orientation == "land" {
+				ls.Width, ls.Height = spec.Height, spec.Width
+			}
+			dir := path.Join(outputPath, "launchimage", "android", "res", "drawable-"+orientation+"-"+spec.Density)
+			if err = os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			savePath := path.Join(dir, "splash.png")
+
+			log.Println("generating ", savePath)
+			if err = BackgroundForegroundHandler(bm, fm, savePath, &ls); err != nil {
+				return err
+			}
+		}
+	}
+
+	return generateSplashScreenV31(bm, fm, path.Join(outputPath, "launchimage", "android", "res"))
+}
+
+// generateSplashScreenV31 writes the icon, the optional branding image and the theme snippet for the Android 12 SplashScreen API.
+func generateSplashScreenV31(bm image.Image, fm image.Image, resDir string) error {
+	var bc color.NRGBA
+	var err error
+	if backgroundColor != "" {
+		if bc, err = util.ParseColor(backgroundColor); err != nil {
+			log.Println(backgroundColor, err)
+			return err
+		}
+		bc.A = 0xff
+	} else {
+		bc = util.DominantColor(bm)
+	}
+
+	var brm image.Image
+	if brandingImagePath != "" {
+		reader, err := util.OpenURI(brandingImagePath)
+		if err != nil {
+			log.Println(brandingImagePath, err)
+			return err
+		}
+		defer reader.Close()
+		if brm, _, err = util.ImageDecode(reader); err != nil {
+			log.Println(brandingImagePath, err)
+			return err
+		}
+	}
+
+	for _, density := range AndroidDensities {
+		if density.Scale < 1 {
+			continue
+		}
+		dir := path.Join(resDir, "drawable-"+density.Name)
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		length := density.Pixels(splashScreenIconDp)
+		// the icon is clipped by a circle, so the foreground must fit in the square inscribed in it
+		content := int(float64(density.Pixels(splashScreenIconMaskDp)) / math.Sqrt2)
+		fn := path.Join(dir, "splash_icon.png")
+		if err = saveAndCrush(util.Center(util.Fit(fm, content, content), length, length), fn); err != nil {
+			log.Println(fn, err)
+		}
+		if brm != nil {
+			w, h := density.Pixels(splashScreenBrandingWidthDp), density.Pixels(splashScreenBrandingHeightDp)
+			fn = path.Join(dir, "splash_branding.png")
+			if err = saveAndCrush(util.Center(util.Fit(brm, w, h), w, h), fn); err != nil {
+				log.Println(fn, err)
+			}
+		}
+	}
+
+	branding := ""
+	if brm != nil {
+		branding = splashScreenBrandingXml
+	}
+	if err = os.MkdirAll(path.Join(resDir, "values-v31"), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf(splashScreenThemeXml, util.HexColor(bc), branding)
+	return ioutil.WriteFile(path.Join(resDir, "values-v31", "themes.xml"), []byte(content), 0644)
 }
 
 func GenerateLauncherIcon(origin string) error {
@@ -53,15 +183,472 @@ func GenerateLauncherIcon(origin string) error {
 		return err
 	}
 
+	return writeFormFactorLauncherIcons(m, path.Join(outputPath, "appicon", "android"))
+}
+
+// writeFormFactorLauncherIcons writes the launcher icons of every requested form factor, the phone ones into
+// androidDir/res and the others into androidDir/<form factor>/res.
+func writeFormFactorLauncherIcons(m image.Image, androidDir string) (err error) {
+	for _, name := range formFactors {
+		var formFactor *FormFactorSpec
+		for i := range FormFactorSpecifications {
+			if FormFactorSpecifications[i].Name == name {
+				formFactor = &FormFactorSpecifications[i]
+			}
+		}
+		if formFactor == nil {
+			return errors.New("unknown form factor " + name)
+		}
+
+		resDir := path.Join(androidDir, "res")
+		if formFactor.Name != "phone" {
+			resDir = path.Join(androidDir, formFactor.Name, "res")
+		}
+		log.Println("generating", formFactor.Name, "launcher icons in", resDir)
+		if err = writeLauncherIcons(m, resDir, formFactor.Round); err != nil {
+			return err
+		}
+		if formFactor.Banner {
+			if err = writeTVBanner(m, resDir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeLauncherIcons writes the square and round legacy launcher icons into the mipmap directories under resDir,
+// masking the square ones with a circle as well when round is set.
+func writeLauncherIcons(m image.Image, resDir string, round bool) error {
 	for _, spec := range LauncherIconSpecifications {
+		dir := path.Join(resDir, "mipmap-"+spec.Density)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
 		im := resize.Resize(uint(spec.Length), uint(spec.Length), m, resize.Bilinear)
-		if err := util.SaveImage(im, spec.Name, util.IT_png); err != nil {
-			log.Println(spec.Name, err)
+		fn := path.Join(dir, resourceName+".png")
+		if round {
+			im = util.CircleCrop(im)
+		}
+		if err := saveAndCrush(im, fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+		fn = path.Join(dir, resourceName+"_round.png")
+		if err := saveAndCrush(util.CircleCrop(im), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTVBanner composes the Android TV home screen banner from the background and the foreground,
+// falling back to the launcher icon when no foreground is given.
+func writeTVBanner(m image.Image, resDir string) error {
+	bm, err := loadBackground()
+	if err != nil {
+		return err
+	}
+	fm := m
+	if foregroundImagePath != "" {
+		if fm, err = loadForeground(); err != nil {
+			return err
+		}
+	}
+
+	dir := path.Join(resDir, "drawable-xhdpi")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fn := path.Join(dir, "banner.png")
+	log.Println("generating ", fn)
+	return BackgroundForegroundHandler(bm, fm, fn, &launchImageSpec{Width: tvBannerWidth, Height: tvBannerHeight})
+}
+
+const (
+	tvBannerWidth  = 320
+	tvBannerHeight = 180
+
+	playStoreIconLength     = 512
+	playStoreIconMaxSize    = 1024 * 1024
+	playStoreFeatureWidth   = 1024
+	playStoreFeatureHeight  = 500
+	playStoreFeatureMaxSize = 15 * 1024 * 1024
+
+	splashScreenIconDp           = 240
+	splashScreenIconMaskDp       = 160
+	splashScreenBrandingWidthDp  = 200
+	splashScreenBrandingHeightDp = 80
+
+	splashScreenThemeXml = `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <style name="Theme.App.Starting" parent="android:Theme.Material.Light.NoActionBar">
+        <item name="android:windowSplashScreenBackground">%s</item>
+        <item name="android:windowSplashScreenAnimatedIcon">@drawable/splash_icon</item>
+%s    </style>
+</resources>
+`
+	splashScreenBrandingXml = `        <item name="android:windowSplashScreenBrandingImage">@drawable/splash_branding</item>
+`
+
+	notificationIconDp        = 24
+	notificationIconContentDp = 20
+	// silhouettes with less transparent area than this are likely a solid square on the status bar
+	minNotificationTransparentRatio = 0.1
+
+	adaptiveIconLayerDp    = 108
+	adaptiveIconSafeZoneDp = 72
+
+	adaptiveIconXml = `<?xml version="1.0" encoding="utf-8"?>
+<adaptive-icon xmlns:android="http://schemas.android.com/apk/res/android">
+    <background android:drawable="%s"/>
+    <foreground android:drawable="@mipmap/%s_foreground"/>
+%s</adaptive-icon>
+`
+	monochromeXml = `    <monochrome android:drawable="@mipmap/%s_monochrome"/>
+`
+	colorResourceXml = `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <color name="%s">%s</color>
+</resources>
+`
+)
+
+func GenerateAdaptiveIcon() error {
+	origin := foregroundImagePath
+	if origin == "" {
+		origin = inputPath
+	}
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	fm, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	var bm image.Image
+	var bc color.NRGBA
+	if backgroundColor != "" {
+		if bc, err = util.ParseColor(backgroundColor); err != nil {
+			log.Println(backgroundColor, err)
+			return err
+		}
+	} else if backgroundImagePath != "" {
+		r, err := util.OpenURI(backgroundImagePath)
+		if err != nil {
+			log.Println(backgroundImagePath, err)
+			return err
+		}
+		defer r.Close()
+		if bm, _, err = util.ImageDecode(r); err != nil {
+			log.Println(backgroundImagePath, err)
+			return err
+		}
+	} else {
+		return errors.New("adaptive icon requires a background image or a background color")
+	}
+
+	resDir := path.Join(outputPath, "appicon", "android", "res")
+	for _, density := range AndroidDensities {
+		dir := path.Join(resDir, "mipmap-"+density.Name)
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		layer := density.Pixels(adaptiveIconLayerDp)
+		safeZone := density.Pixels(adaptiveIconSafeZoneDp)
+
+		foreground := util.Center(util.Fit(fm, safeZone, safeZone), layer, layer)
+		fn := path.Join(dir, resourceName+"_foreground.png")
+		if err = saveAndCrush(foreground, fn); err != nil {
+			log.Println(fn, err)
+		}
+		if monochromeIcon {
+			fn = path.Join(dir, resourceName+"_monochrome.png")
+			if err = saveAndCrush(util.Monochrome(foreground, uint8(monochromeThreshold)), fn); err != nil {
+				log.Println(fn, err)
+			}
+		}
+		if bm != nil {
+			fn = path.Join(dir, resourceName+"_background.png")
+			if err = saveAndCrush(util.Cover(bm, layer, layer), fn); err != nil {
+				log.Println(fn, err)
+			}
+		}
+	}
+
+	if err = writeLauncherIcons(composeLegacyIcon(fm, bm, bc), resDir, false); err != nil {
+		return err
+	}
+
+	background := "@mipmap/" + resourceName + "_background"
+	if bm == nil {
+		background = "@color/" + resourceName + "_background"
+		if err = os.MkdirAll(path.Join(resDir, "values"), 0755); err != nil {
+			return err
+		}
+		content := fmt.Sprintf(colorResourceXml, resourceName+"_background", util.HexColor(bc))
+		if err = ioutil.WriteFile(path.Join(resDir, "values", resourceName+"_background.xml"), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	if err = os.MkdirAll(path.Join(resDir, "mipmap-anydpi-v26"), 0755); err != nil {
+		return err
+	}
+	monochrome := ""
+	if monochromeIcon {
+		monochrome = fmt.Sprintf(monochromeXml, resourceName)
+	}
+	content := fmt.Sprintf(adaptiveIconXml, background, resourceName, monochrome)
+	for _, name := range []string{resourceName + ".xml", resourceName + "_round.xml"} {
+		if err = ioutil.WriteFile(path.Join(resDir, "mipmap-anydpi-v26", name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// composeLegacyIcon flattens the adaptive layers into the visible 72dp area for launchers older than API 26.
+func composeLegacyIcon(fm image.Image, bm image.Image, bc color.NRGBA) image.Image {
+	density := AndroidDensities[len(AndroidDensities)-1]
+	layer := density.Pixels(adaptiveIconLayerDp)
+	safeZone := density.Pixels(adaptiveIconSafeZoneDp)
+	offset := (layer - safeZone) / 2
+
+	m := image.NewRGBA(image.Rect(0, 0, safeZone, safeZone))
+	if bm != nil {
+		draw.Draw(m, m.Bounds(), util.Cover(bm, layer, layer), image.Point{offset, offset}, draw.Src)
+	} else {
+		draw.Draw(m, m.Bounds(), &image.Uniform{bc}, image.ZP, draw.Src)
+	}
+	draw.Draw(m, m.Bounds(), util.Center(util.Fit(fm, safeZone, safeZone), safeZone, safeZone), image.ZP, draw.Over)
+	return m
+}
+
+func GenerateNotificationIcon(origin string) error {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	m = util.TransparentImage(m, red, green, blue, transparentWhiteDirect)
+	if ratio := util.TransparentRatio(m); ratio < minNotificationTransparentRatio {
+		log.Printf("warning: only %.1f%% of %s is transparent, the notification icon will probably show as a solid shape\n", ratio*100, origin)
+	}
+	m = util.Monochrome(m, 0)
+
+	name := "ic_stat_" + androidResourceName(origin)
+	for _, density := range AndroidDensities {
+		if density.Scale < 1 {
+			continue
+		}
+		dir := path.Join(outputPath, "notification", "android", "res", "drawable-"+density.Name)
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		length := density.Pixels(notificationIconDp)
+		content := density.Pixels(notificationIconContentDp)
+		fn := path.Join(dir, name+".png")
+		if err = saveAndCrush(util.Center(util.Fit(m, content, content), length, length), fn); err != nil {
+			log.Println(fn, err)
+		}
+	}
+	return nil
+}
+
+// androidResourceName turns a file name into a valid Android resource name.
+func androidResourceName(fn string) string {
+	base := strings.ToLower(filepath.Base(fn))
+	base = base[:len(base)-len(filepath.Ext(base))]
+	name := []byte(base)
+	for i, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+func GenerateDrawables(origin string) error {
+	var base *AndroidDensity
+	for i := range AndroidDensities {
+		if AndroidDensities[i].Name == baseDensity {
+			base = &AndroidDensities[i]
+		}
+	}
+	if base == nil {
+		return errors.New("unknown base density " + baseDensity)
+	}
+
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	ninePatch := strings.HasSuffix(strings.ToLower(origin), ".9.png")
+	name := origin
+	if ninePatch {
+		name = name[:len(name)-len(".9.png")] + ".png"
+	}
+	name = androidResourceName(name)
+	if webpOutput && ninePatch {
+		log.Println(origin, "nine-patch images are always written as PNG")
+	}
+
+	for _, density := range AndroidDensities {
+		ratio := density.Scale / base.Scale
+		if ratio > 1 {
+			log.Println("warning: upscaling", origin, "to", density.Name)
+		}
+		dir := path.Join(outputPath, "drawable-"+density.Name)
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		if ninePatch {
+			im, err := util.ScaleNinePatch(m, ratio)
+			if err != nil {
+				log.Println(origin, density.Name, err)
+				continue
+			}
+			// crushing quantizes colors and may break the pure black markers
+			fn := path.Join(dir, name+".9.png")
+			if err = util.SaveImage(im, fn, util.IT_png); err != nil {
+				log.Println(fn, err)
+			}
+			continue
+		}
+
+		sz := m.Bounds().Size()
+		im := resize.Resize(uint(float64(sz.X)*ratio+0.5), uint(float64(sz.Y)*ratio+0.5), m, resize.Bilinear)
+		if webpOutput {
+			fn := path.Join(dir, name+".webp")
+			if err = util.SaveImage(im, fn, util.IT_webp); err != nil {
+				log.Println(fn, err)
+			}
 			continue
 		}
-		if err = util.DoCrush(compress, spec.Name); err != nil {
-			log.Println(spec.Name, err)
+		fn := path.Join(dir, name+".png")
+		if err = saveAndCrush(im, fn); err != nil {
+			log.Println(fn, err)
+		}
+	}
+	return nil
+}
+
+func GeneratePlayStoreAssets() error {
+	if inputPath == "" && foregroundImagePath == "" {
+		return errors.New("play store assets require an icon input or a foreground image")
+	}
+	dir := path.Join(outputPath, "playstore", "android")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var failed bool
+	if inputPath != "" {
+		reader, err := util.OpenURI(inputPath)
+		if err != nil {
+			log.Println(inputPath, err)
+			return err
+		}
+		defer reader.Close()
+		m, _, err := util.ImageDecode(reader)
+		if err != nil {
+			log.Println(inputPath, err)
+			return err
+		}
+
+		// Play requires a 32-bit PNG, so the icon is compressed losslessly instead of crushed to a palette
+		// and keeps its alpha channel
+		fn := path.Join(dir, "ic_launcher-playstore.png")
+		im := resize.Resize(playStoreIconLength, playStoreIconLength, m, resize.Bilinear)
+		if err = util.CompressPNG(util.ForceAlpha(im), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+		for _, problem := range checkPlayStoreAsset(fn, playStoreIconLength, playStoreIconLength, playStoreIconMaxSize, util.PNGColorRGBA) {
+			log.Println(fn, problem)
+			failed = true
+		}
+	}
+
+	if foregroundImagePath != "" {
+		bm, fm, err := loadBackgroundForeground()
+		if err != nil {
+			return err
+		}
+		m, err := composeBackgroundForeground(bm, fm, &launchImageSpec{Width: playStoreFeatureWidth, Height: playStoreFeatureHeight})
+		if err != nil {
+			return err
+		}
+		// the feature graphic must not have an alpha channel
+		bc := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+		if backgroundColor != "" {
+			if bc, err = util.ParseColor(backgroundColor); err != nil {
+				log.Println(backgroundColor, err)
+				return err
+			}
+			bc.A = 0xff
+		}
+		fn := path.Join(dir, "feature-graphic.png")
+		if err = util.CompressPNG(util.Flatten(m, bc), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+		for _, problem := range checkPlayStoreAsset(fn, playStoreFeatureWidth, playStoreFeatureHeight, playStoreFeatureMaxSize, util.PNGColorRGB) {
+			log.Println(fn, problem)
+			failed = true
 		}
 	}
+
+	if failed {
+		return errors.New("generated play store assets do not meet the play console requirements")
+	}
 	return nil
 }
+
+// checkPlayStoreAsset verifies a generated PNG against the dimensions, file size and color type Play Console accepts.
+func checkPlayStoreAsset(fn string, width, height int, maxSize int64, colorType uint8) (problems []string) {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if fi.Size() > maxSize {
+		problems = append(problems, fmt.Sprintf("file size %d bytes exceeds the limit of %d bytes", fi.Size(), maxSize))
+	}
+	header, err := util.ReadPNGHeader(fn)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	if header.Width != width || header.Height != height {
+		problems = append(problems, fmt.Sprintf("size is %dx%d, expected %dx%d", header.Width, header.Height, width, height))
+	}
+	if header.BitDepth != 8 || header.ColorType != colorType {
+		expected := "24-bit PNG without alpha"
+		if colorType == util.PNGColorRGBA {
+			expected = "32-bit PNG with alpha"
+		}
+		problems = append(problems, fmt.Sprintf("is not a %s (bit depth %d, color type %d)", expected, header.BitDepth, header.ColorType))
+	}
+	return problems
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/missdeer/yairc/util"
+	"github.com/nfnt/resize"
+)
+
+var (
+	// electron-builder takes the linux icon set from the <size>x<size>.png files in build/icons
+	electronIconSizes = []int{16, 24, 32, 48, 64, 128, 256, 512, 1024}
+
+	cordovaConfigXml = template.Must(template.New("config.xml").Parse(`<!-- merge into the <widget> element of config.xml -->
+<platform name="android">
+{{- range .Android}}
+    <icon density="{{.Density}}" src="{{.Src}}" />
+{{- end}}
+</platform>
+<platform name="ios">
+{{- range .IOS}}
+    <icon height="{{.Length}}" src="{{.Src}}" width="{{.Length}}" />
+{{- end}}
+</platform>
+`))
+
+	qtResourceFile = template.Must(template.New("icons.qrc").Parse(`<!DOCTYPE RCC>
+<RCC version="1.0">
+    <qresource prefix="/">
+{{- range .}}
+        <file>{{.}}</file>
+{{- end}}
+    </qresource>
+</RCC>
+`))
+)
+
+type cordovaIcon struct {
+	Density string
+	Length  int
+	Src     string
+}
+
+// GenerateFrameworkIcons writes the app icons of a cross-platform framework project into the paths the framework expects,
+// with outputPath as the project root.
+func GenerateFrameworkIcons(origin string, framework string) error {
+	data, m, err := readIconSource(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	switch framework {
+	case "flutter":
+		if err = writeIOSAppIcon(origin, data, m, path.Join(outputPath, "ios", "Runner", "Assets.xcassets")); err != nil {
+			return err
+		}
+		return writeFrameworkLauncherIcons(m)
+	case "react-native":
+		name := appName
+		if name == "" {
+			return fmt.Errorf("--app-name is required to locate ios/<app-name>/Images.xcassets")
+		}
+		if err = writeIOSAppIcon(origin, data, m, path.Join(outputPath, "ios", name, "Images.xcassets")); err != nil {
+			return err
+		}
+		return writeFrameworkLauncherIcons(m)
+	case "capacitor":
+		if err = writeIOSAppIcon(origin, data, m, path.Join(outputPath, "ios", "App", "App", "Assets.xcassets")); err != nil {
+			return err
+		}
+		return writeFrameworkLauncherIcons(m)
+	case "cordova":
+		return writeCordovaIcons(origin, m)
+	case "electron":
+		return writeElectronIcons(squareAppIcon(m), path.Join(outputPath, "build"))
+	case "qt":
+		return writeQtIcons(squareAppIcon(m), outputPath)
+	}
+	return fmt.Errorf("unsupported framework %s", framework)
+}
+
+// writeFrameworkLauncherIcons writes the phone launcher icons into the main source set of android/app,
+// other form factors have no source set of their own there.
+func writeFrameworkLauncherIcons(m image.Image) error {
+	resDir := path.Join(outputPath, "android", "app", "src", "main", "res")
+	log.Println("generating launcher icons in", resDir)
+	return writeLauncherIcons(squareAppIcon(m), resDir, false)
+}
+
+// writeCordovaIcons writes the ios icons and the android launcher icons under res/icon,
+// and the <icon> entries to merge into config.xml.
+func writeCordovaIcons(origin string, m image.Image) error {
+	bm, err := prepareAppIcon(m)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	var params struct {
+		Android []cordovaIcon
+		IOS     []cordovaIcon
+	}
+
+	iosDir := path.Join("res", "icon", "ios")
+	if err = os.MkdirAll(path.Join(outputPath, iosDir), 0755); err != nil {
+		return err
+	}
+	written := make(map[string]bool)
+	for _, spec := range appIconSpecifications {
+		if written[spec.Filename] {
+			continue
+		}
+		written[spec.Filename] = true
+		src := path.Join(iosDir, spec.Filename)
+		fn := path.Join(outputPath, src)
+		if err = saveAndCrush(resize.Resize(uint(spec.Length()), uint(spec.Length()), bm, resize.Bilinear), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+		params.IOS = append(params.IOS, cordovaIcon{Length: spec.Length(), Src: src})
+	}
+
+	androidDir := path.Join("res", "icon", "android")
+	if err = writeLauncherIcons(squareAppIcon(m), path.Join(outputPath, androidDir), false); err != nil {
+		return err
+	}
+	for _, spec := range LauncherIconSpecifications {
+		params.Android = append(params.Android, cordovaIcon{
+			Density: spec.Density,
+			Length:  spec.Length,
+			Src:     path.Join(androidDir, "mipmap-"+spec.Density, resourceName+".png"),
+		})
+	}
+
+	fn := path.Join(outputPath, "res", "icon", "config.xml")
+	log.Println("generating", fn)
+	fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return cordovaConfigXml.Execute(fd, params)
+}
+
+// writeElectronIcons writes icon.icns and icon.ico into buildDir, the default buildResources of electron-builder,
+// and the linux png set into buildDir/icons.
+func writeElectronIcons(m image.Image, buildDir string) error {
+	iconsDir := path.Join(buildDir, "icons")
+	if err := os.MkdirAll(iconsDir, 0755); err != nil {
+		return err
+	}
+	for _, size := range electronIconSizes {
+		fn := path.Join(iconsDir, fmt.Sprintf("%dx%d.png", size, size))
+		if err := saveAndCrush(resize.Resize(uint(size), uint(size), m, resize.Bilinear), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+	}
+
+	fn := path.Join(buildDir, "icon.icns")
+	log.Println("generating", fn)
+	fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = util.EncodeICNS(fd, m)
+	fd.Close()
+	if err != nil {
+		log.Println(fn, err)
+		return err
+	}
+
+	fn = path.Join(buildDir, "icon.ico")
+	log.Println("generating", fn)
+	fd, err = os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return util.EncodeICO(fd, m, util.ICOSizes)
+}
+
+// writeQtIcons writes icons/icon_<size>.png for every hicolor size, app.ico and app.icns for RC_ICONS and ICON,
+// and an icons.qrc that lists the png files.
+func writeQtIcons(m image.Image, root string) error {
+	dir := path.Join(root, "icons")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var files []string
+	for _, size := range hicolorSizes {
+		name := path.Join("icons", fmt.Sprintf("icon_%d.png", size))
+		fn := path.Join(root, name)
+		if err := saveAndCrush(resize.Resize(uint(size), uint(size), m, resize.Bilinear), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+		files = append(files, name)
+	}
+
+	for _, icon := range []struct {
+		filename string
+		encode   func(fd *os.File) error
+	}{
+		{"app.ico", func(fd *os.File) error { return util.EncodeICO(fd, m, util.ICOSizes) }},
+		{"app.icns", func(fd *os.File) error { return util.EncodeICNS(fd, m) }},
+	} {
+		fn := path.Join(dir, icon.filename)
+		log.Println("generating", fn)
+		fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		err = icon.encode(fd)
+		fd.Close()
+		if err != nil {
+			log.Println(fn, err)
+			return err
+		}
+	}
+
+	fn := path.Join(root, "icons.qrc")
+	log.Println("generating", fn)
+	fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return qtResourceFile.Execute(fd, files)
+}
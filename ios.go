@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
@@ -8,6 +10,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/missdeer/golib/fsutil"
@@ -19,261 +22,161 @@ import (
 type handler func(image.Image, image.Image, string, *launchImageSpec) error
 
 type launchImageSpec struct {
-	Width   int
-	Height  int
-	Postfix string
-	Handler handler
+	Width    int
+	Height   int
+	Postfix  string
+	Handler  handler
+	SafeArea safeAreaInsets
 }
 
+// safeAreaInsets are the pixels on each edge covered by the status bar, the sensor housing or the home indicator.
+type safeAreaInsets struct {
+	Top    int
+	Left   int
+	Bottom int
+	Right  int
+}
+
+// launchLayout places the foreground of a launch image, Size and the offsets are fractions of the short edge.
+type launchLayout struct {
+	Size      float64
+	Anchor    string
+	OffsetX   float64
+	OffsetY   float64
+	MaxPixels int
+}
+
+const (
+	modernAppIconLength = 1024
+	// per channel difference to the corner color still treated as matte when deriving the dark icon
+	darkMatteTolerance = 24
+	// size fills are rendered at for consumers that only inspect the background, such as DominantColor
+	fillPreviewLength = 64
+)
+
+// appIconSpec describes one entry of AppIcon.appiconset, Size is in points.
 type appIconSpec struct {
-	Length int
-	Name   string
+	Size     float64
+	Idiom    string
+	Scale    int
+	Role     string
+	Subtype  string
+	Filename string
+}
+
+func (spec appIconSpec) Length() int {
+	return int(spec.Size*float64(spec.Scale) + 0.5)
+}
+
+func (spec appIconSpec) assetImage() assetImage {
+	size := strconv.FormatFloat(spec.Size, 'f', -1, 64)
+	return assetImage{
+		Filename: spec.Filename,
+		Idiom:    spec.Idiom,
+		Role:     spec.Role,
+		Scale:    fmt.Sprintf("%dx", spec.Scale),
+		Size:     size + "x" + size,
+		Subtype:  spec.Subtype,
+	}
 }
 
 var (
 	launchImageSpecifications = []launchImageSpec{
-		{640, 960, "LaunchImage-iOS7@2x~iphone.png", BackgroundForegroundHandler},
-		{960, 640, "LaunchImage-iOS7-Landscape@2x~iphone.png", BackgroundForegroundHandler},
-		{640, 1136, "LaunchImage-iOS7-568h@2x~iphone.png", BackgroundForegroundHandler},
-		{1136, 640, "LaunchImage-iOS7-Landscape-568h@2x~iphone.png", BackgroundForegroundHandler},
-		{750, 1334, "LaunchImage-375w-667h@2x~iphone.png", BackgroundForegroundHandler},
-		{1334, 750, "LaunchImage-Landscape-375w-667h@2x~iphone.png", BackgroundForegroundHandler},
-		{1242, 2208, "LaunchImage-414w-736h@3x~iphone.png", BackgroundForegroundHandler},
-		{2208, 1242, "LaunchImage-Landscape-414w-736h@3x~iphone.png", BackgroundForegroundHandler},
-		{768, 1024, "LaunchImage-iOS7-Portrait~ipad.png", BackgroundForegroundHandler},
-		{1024, 768, "LaunchImage-iOS7-Landscape~ipad.png", BackgroundForegroundHandler},
-		{1536, 2048, "LaunchImage-iOS7-Portrait@2x~ipad.png", BackgroundForegroundHandler},
-		{2048, 1536, "LaunchImage-iOS7-Landscape@2x~ipad.png", BackgroundForegroundHandler},
-		{1668, 2224, "LaunchImage-Portrait-1112@2x.png", BackgroundForegroundHandler},
-		{2224, 1668, "LaunchImage-Landscape-1112@2x.png", BackgroundForegroundHandler},
-		{1125, 2436, "LaunchImage-375w-812h@3x.png", BackgroundForegroundHandler},
-		{2436, 1125, "LaunchImage-Landscape-375w-812h@3x.png", BackgroundForegroundHandler},
-		{2048, 2732, "LaunchImage-Portrait@2x.png", BackgroundForegroundHandler},
-		{2732, 2048, "LaunchImage-Landscape@2x.png", BackgroundForegroundHandler},
+		{640, 960, "LaunchImage-iOS7@2x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{960, 640, "LaunchImage-iOS7-Landscape@2x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{640, 1136, "LaunchImage-iOS7-568h@2x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{1136, 640, "LaunchImage-iOS7-Landscape-568h@2x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{750, 1334, "LaunchImage-375w-667h@2x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{1334, 750, "LaunchImage-Landscape-375w-667h@2x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{1242, 2208, "LaunchImage-414w-736h@3x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{2208, 1242, "LaunchImage-Landscape-414w-736h@3x~iphone.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{768, 1024, "LaunchImage-iOS7-Portrait~ipad.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{1024, 768, "LaunchImage-iOS7-Landscape~ipad.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{1536, 2048, "LaunchImage-iOS7-Portrait@2x~ipad.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{2048, 1536, "LaunchImage-iOS7-Landscape@2x~ipad.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{1668, 2224, "LaunchImage-Portrait-1112@2x.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{2224, 1668, "LaunchImage-Landscape-1112@2x.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{1125, 2436, "LaunchImage-375w-812h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Top: 132, Bottom: 102}},
+		{2436, 1125, "LaunchImage-Landscape-375w-812h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Left: 132, Bottom: 63, Right: 132}},
+		{828, 1792, "LaunchImage-414w-896h@2x.png", BackgroundForegroundHandler, safeAreaInsets{Top: 88, Bottom: 68}},
+		{1792, 828, "LaunchImage-Landscape-414w-896h@2x.png", BackgroundForegroundHandler, safeAreaInsets{Left: 88, Bottom: 42, Right: 88}},
+		{1242, 2688, "LaunchImage-414w-896h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Top: 132, Bottom: 102}},
+		{2688, 1242, "LaunchImage-Landscape-414w-896h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Left: 132, Bottom: 63, Right: 132}},
+		{1170, 2532, "LaunchImage-390w-844h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Top: 141, Bottom: 102}},
+		{2532, 1170, "LaunchImage-Landscape-390w-844h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Left: 141, Bottom: 63, Right: 141}},
+		{1284, 2778, "LaunchImage-428w-926h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Top: 141, Bottom: 102}},
+		{2778, 1284, "LaunchImage-Landscape-428w-926h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Left: 141, Bottom: 63, Right: 141}},
+		// the Dynamic Island takes a taller status bar
+		{1179, 2556, "LaunchImage-393w-852h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Top: 177, Bottom: 102}},
+		{2556, 1179, "LaunchImage-Landscape-393w-852h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Left: 177, Bottom: 63, Right: 177}},
+		{1290, 2796, "LaunchImage-430w-932h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Top: 177, Bottom: 102}},
+		{2796, 1290, "LaunchImage-Landscape-430w-932h@3x.png", BackgroundForegroundHandler, safeAreaInsets{Left: 177, Bottom: 63, Right: 177}},
+		{2048, 2732, "LaunchImage-Portrait@2x.png", BackgroundForegroundHandler, safeAreaInsets{}},
+		{2732, 2048, "LaunchImage-Landscape@2x.png", BackgroundForegroundHandler, safeAreaInsets{}},
 	}
 	appIconSpecifications = []appIconSpec{
-		// Recommended if you have a Settings bundle, optional otherwise
-		{29, "AppIcon29x29.png"},
-		{29, "AppIcon29x29.png"},
-		{58, "AppIcon29x29@2x.png"},
-		{58, "AppIcon29x29@2x.png"},
-		{87, "AppIcon29x29@3x.png"},
-		{57, "AppIcon57x57.png"},
-		{114, "AppIcon57x57@2x.png"},
-		{144, "AppIcon72x72@2x.png"},
-		{72, "AppIcon72x72.png"},
-		// Spotlight
-		{40, "AppIcon40x40.png"},
-		{80, "AppIcon40x40@2x.png"},
-		{80, "AppIcon40x40@2x.png"},
-		{50, "AppIcon50x50.png"},
-		{100, "AppIcon50x50@2x.png"},
-		{120, "AppIcon40x40@3x.png"},
-		// Home screen on iPad
-		{76, "AppIcon76x76.png"},
-		{152, "AppIcon76x76@2x.png"},
+		// Notification, Settings, Spotlight and home screen on iPhone
+		{20, "iphone", 2, "", "", "AppIcon20x20@2x.png"},
+		{20, "iphone", 3, "", "", "AppIcon20x20@3x.png"},
+		{29, "iphone", 2, "", "", "AppIcon29x29@2x.png"},
+		{29, "iphone", 3, "", "", "AppIcon29x29@3x.png"},
+		{40, "iphone", 2, "", "", "AppIcon40x40@2x.png"},
+		{40, "iphone", 3, "", "", "AppIcon40x40@3x.png"},
+		{60, "iphone", 2, "", "", "AppIcon60x60@2x.png"},
+		{60, "iphone", 3, "", "", "AppIcon60x60@3x.png"},
+		// Notification, Settings, Spotlight and home screen on iPad
+		{20, "ipad", 1, "", "", "AppIcon20x20.png"},
+		{20, "ipad", 2, "", "", "AppIcon20x20@2x.png"},
+		{29, "ipad", 1, "", "", "AppIcon29x29.png"},
+		{29, "ipad", 2, "", "", "AppIcon29x29@2x.png"},
+		{40, "ipad", 1, "", "", "AppIcon40x40.png"},
+		{40, "ipad", 2, "", "", "AppIcon40x40@2x.png"},
+		{76, "ipad", 1, "", "", "AppIcon76x76.png"},
+		{76, "ipad", 2, "", "", "AppIcon76x76@2x.png"},
 		// Home screen on iPad Pro
-		{167, "AppIcon83.5x83.5@2x.png"},
-		// Home screen on iPhone/iPod Touch with retina display
-		{20, "AppIcon20x20.png"},
-		{40, "AppIcon20x20@2x.png"},
-		{60, "AppIcon20x20@3x.png"},
-		{120, "AppIcon60x60@2x.png"},
-		{180, "AppIcon60x60@3x.png"},
-		// iWatch
-		{48, "AppIcon24@2x.png"},
-		{55, "AppIcon27.5@2x.png"},
-		{58, "AppIcon29@2x.png"},
-		{80, "AppIcon40@2x.png"},
-		{87, "AppIcon29@3x.png"},
-		{88, "AppIcon44@2x.png"},
-		{172, "AppIcon86@2x.png"},
-		{196, "AppIcon98@2x.png"},
-		// App list in iTunes
-		// {512, "iTunesArtwork.png"},
-		{1024, "iTunesArtwork@2x.png"},
-	}
-
-	contentsJson = `{
-	"images": [
-		{
-			"size": "20x20",
-			"idiom": "iphone",
-			"filename": "AppIcon20x20@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "20x20",
-			"idiom": "iphone",
-			"filename": "AppIcon20x20@3x.png",
-			"scale": "3x"
-		},
-		{
-			"size": "29x29",
-			"idiom": "iphone",
-			"filename": "AppIcon29x29@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "29x29",
-			"idiom": "iphone",
-			"filename": "AppIcon29x29@3x.png",
-			"scale": "3x"
-		},
-		{
-			"size": "40x40",
-			"idiom": "iphone",
-			"filename": "AppIcon40x40@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "40x40",
-			"idiom": "iphone",
-			"filename": "AppIcon40x40@3x.png",
-			"scale": "3x"
-		},
-		{
-			"size": "60x60",
-			"idiom": "iphone",
-			"filename": "AppIcon60x60@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "60x60",
-			"idiom": "iphone",
-			"filename": "AppIcon60x60@3x.png",
-			"scale": "3x"
-		},
-		{
-			"size": "20x20",
-			"idiom": "ipad",
-			"filename": "AppIcon20x20.png",
-			"scale": "1x"
-		},
-		{
-			"size": "20x20",
-			"idiom": "ipad",
-			"filename": "AppIcon20x20@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "29x29",
-			"idiom": "ipad",
-			"filename": "AppIcon29x29.png",
-			"scale": "1x"
-		},
-		{
-			"size": "29x29",
-			"idiom": "ipad",
-			"filename": "AppIcon29x29@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "40x40",
-			"idiom": "ipad",
-			"filename": "AppIcon40x40.png",
-			"scale": "1x"
-		},
-		{
-			"size": "40x40",
-			"idiom": "ipad",
-			"filename": "AppIcon40x40@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "76x76",
-			"idiom": "ipad",
-			"filename": "AppIcon76x76.png",
-			"scale": "1x"
-		},
-		{
-			"size": "76x76",
-			"idiom": "ipad",
-			"filename": "AppIcon76x76@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "83.5x83.5",
-			"idiom": "ipad",
-			"filename": "AppIcon83.5x83.5@2x.png",
-			"scale": "2x"
-		},
-		{
-			"size": "1024x1024",
-			"idiom": "ios-marketing",
-			"filename": "iTunesArtwork@2x.png",
-			"scale": "1x"
-		},
-		{
-			"size": "24x24",
-			"idiom": "watch",
-			"scale": "2x",
-			"filename": "AppIcon24@2x.png",
-			"role": "notificationCenter",
-			"subtype": "38mm"
-		},
-		{
-			"size": "27.5x27.5",
-			"idiom": "watch",
-			"scale": "2x",
-			"filename": "AppIcon27.5@2x.png",
-			"role": "notificationCenter",
-			"subtype": "42mm"
-		},
-		{
-			"size": "29x29",
-			"idiom": "watch",
-			"filename": "AppIcon29@2x.png",
-			"role": "companionSettings",
-			"scale": "2x"
-		},
-		{
-			"size": "29x29",
-			"idiom": "watch",
-			"filename": "AppIcon29@3x.png",
-			"role": "companionSettings",
-			"scale": "3x"
-		},
-		{
-			"size": "40x40",
-			"idiom": "watch",
-			"scale": "2x",
-			"filename": "AppIcon40@2x.png",
-			"role": "appLauncher",
-			"subtype": "38mm"
-		},
-		{
-			"size": "44x44",
-			"idiom": "watch",
-			"scale": "2x",
-			"filename": "AppIcon44@2x.png",
-			"role": "longLook",
-			"subtype": "42mm"
-		},
-		{
-			"size": "86x86",
-			"idiom": "watch",
-			"scale": "2x",
-			"filename": "AppIcon86@2x.png",
-			"role": "quickLook",
-			"subtype": "38mm"
-		},
-		{
-			"size": "98x98",
-			"idiom": "watch",
-			"scale": "2x",
-			"filename": "AppIcon98@2x.png",
-			"role": "quickLook",
-			"subtype": "42mm"
-		}
-	],
-	"properties": {
-		"pre-rendered": true
+		{83.5, "ipad", 2, "", "", "AppIcon83.5x83.5@2x.png"},
+		// App Store
+		{1024, "ios-marketing", 1, "", "", "iTunesArtwork@2x.png"},
 	}
-}`
 )
 
 func BackgroundForegroundHandler(bm image.Image, fm image.Image, savePath string, spec *launchImageSpec) error {
+	m, err := composeBackgroundForeground(bm, fm, spec)
+	if err != nil {
+		log.Println(savePath, err)
+		return err
+	}
+
+	if err = util.SaveImage(m, savePath, util.IT_png); err != nil {
+		log.Println(savePath, err)
+		return err
+	}
+	if isGradient(bm) {
+		return nil
+	}
+	if err = util.DoCrush(compress, savePath); err != nil {
+		log.Println(savePath, err)
+		return err
+	}
+	return nil
+}
+
+// isGradient reports whether bm is a dithered gradient fill, crushing it to a palette would bring the banding back.
+func isGradient(bm image.Image) bool {
+	fi, ok := bm.(*util.FillImage)
+	return ok && fi.Fill.Kind != util.FillSolid
+}
+
+// composeBackgroundForeground crops the background to cover the spec size, or renders the fill at that size,
+// and lays the foreground out on it.
+func composeBackgroundForeground(bm image.Image, fm image.Image, spec *launchImageSpec) (*image.RGBA, error) {
+	if fi, ok := bm.(*util.FillImage); ok {
+		m := fi.Fill.Render(spec.Width, spec.Height)
+		sm := resizeForeground(fm, spec)
+		draw.Draw(m, placeForeground(sm.Bounds().Size(), spec), sm, sm.Bounds().Min, draw.Over)
+		return m, nil
+	}
+
 	im := resize.Resize(0, uint(spec.Height), bm, resize.Bilinear)
 	if im.Bounds().Size().X < spec.Width {
 		im = resize.Resize(uint(spec.Width), 0, bm, resize.Bilinear)
@@ -290,8 +193,7 @@ func BackgroundForegroundHandler(bm image.Image, fm image.Image, savePath string
 			Anchor: image.Point{(im.Bounds().Size().X - spec.Width) / 2, 0},
 		})
 		if err != nil {
-			log.Println(savePath, err)
-			return err
+			return nil, err
 		}
 	}
 	if im.Bounds().Size().Y > spec.Height {
@@ -301,65 +203,127 @@ func BackgroundForegroundHandler(bm image.Image, fm image.Image, savePath string
 			Anchor: image.Point{0, (im.Bounds().Size().Y - spec.Height) / 2},
 		})
 		if err != nil {
-			log.Println(savePath, err)
-			return err
+			return nil, err
 		}
 	}
 
 	m := image.NewRGBA(image.Rect(0, 0, spec.Width, spec.Height))
 	draw.Draw(m, m.Bounds(), im, im.Bounds().Min, draw.Src)
-	if spec.Width < spec.Height {
-		x := spec.Width / 4
-		y := spec.Height/2 - x
-		sm := resize.Resize(uint(x*2), 0, fm, resize.Bilinear)
-		draw.Draw(m, image.Rect(x, y, x*3, y+x*2), sm, image.Point{0, 0}, draw.Over)
-	} else {
-		y := spec.Height / 4
-		x := spec.Width/2 - y
-		sm := resize.Resize(0, uint(y*2), fm, resize.Bilinear)
-		draw.Draw(m, image.Rect(x, y, x+y*2, y*3), sm, image.Point{0, 0}, draw.Over)
-	}
+	sm := resizeForeground(fm, spec)
+	draw.Draw(m, placeForeground(sm.Bounds().Size(), spec), sm, sm.Bounds().Min, draw.Over)
+	return m, nil
+}
 
-	if err = util.SaveImage(m, savePath, util.IT_png); err != nil {
-		log.Println(savePath, err)
-		return err
+// resizeForeground fits fm into the square of the layout size, capped by MaxPixels on its longer edge.
+func resizeForeground(fm image.Image, spec *launchImageSpec) image.Image {
+	layout := foregroundLayout
+	short := spec.Width
+	if spec.Height < short {
+		short = spec.Height
 	}
-	if err = util.DoCrush(compress, savePath); err != nil {
-		log.Println(savePath, err)
-		return err
+	length := int(float64(short) * layout.Size)
+	if layout.MaxPixels > 0 && length > layout.MaxPixels {
+		length = layout.MaxPixels
 	}
-	return nil
+	return util.Fit(fm, length, length)
 }
 
-func GenerateLaunchImage() (err error) {
-	var bm image.Image
-	reader, err := util.OpenURI(backgroundImagePath)
-	if err == nil {
-		bm, _, err = util.ImageDecode(reader)
-		reader.Close()
+// placeForeground returns where a foreground of size sz is drawn, anchored inside the safe area of spec
+// and kept clear of its insets after the offsets are applied.
+func placeForeground(sz image.Point, spec *launchImageSpec) image.Rectangle {
+	layout := foregroundLayout
+	safe := image.Rect(spec.SafeArea.Left, spec.SafeArea.Top, spec.Width-spec.SafeArea.Right, spec.Height-spec.SafeArea.Bottom)
+	short := spec.Width
+	if spec.Height < short {
+		short = spec.Height
 	}
 
-	if err != nil {
-		width := 2
-		height := 2
-
-		upLeft := image.Point{0, 0}
-		lowRight := image.Point{width, height}
-		img := image.NewRGBA(image.Rectangle{upLeft, lowRight})
-		for x := 0; x < width; x++ {
-			for y := 0; y < height; y++ {
-				img.Set(x, y, color.White)
-			}
+	x := safe.Min.X + (safe.Dx()-sz.X)/2
+	var y int
+	switch layout.Anchor {
+	case "top-third":
+		y = safe.Min.Y + safe.Dy()/3 - sz.Y/2
+	case "bottom":
+		y = safe.Max.Y - sz.Y - short/10
+	default:
+		y = safe.Min.Y + (safe.Dy()-sz.Y)/2
+	}
+	x += int(layout.OffsetX * float64(short))
+	y += int(layout.OffsetY * float64(short))
+
+	if x > safe.Max.X-sz.X {
+		x = safe.Max.X - sz.X
+	}
+	if x < safe.Min.X {
+		x = safe.Min.X
+	}
+	if y > safe.Max.Y-sz.Y {
+		y = safe.Max.Y - sz.Y
+	}
+	if y < safe.Min.Y {
+		y = safe.Min.Y
+	}
+	return image.Rect(x, y, x+sz.X, y+sz.Y)
+}
+
+// loadBackground returns the background image, the gradient or the color, only one of them can be given,
+// or plain white when none is. Fills are returned as *util.FillImage so they are rendered at every target size.
+func loadBackground() (image.Image, error) {
+	switch {
+	case backgroundImagePath != "":
+		reader, err := util.OpenURI(backgroundImagePath)
+		if err != nil {
+			log.Println(backgroundImagePath, err)
+			return nil, err
+		}
+		defer reader.Close()
+		bm, _, err := util.ImageDecode(reader)
+		if err != nil {
+			log.Println(backgroundImagePath, err)
+			return nil, err
+		}
+		return bm, nil
+	case backgroundGradient != "":
+		fill, err := util.ParseFill(backgroundGradient)
+		if err != nil {
+			log.Println(backgroundGradient, err)
+			return nil, err
 		}
-		bm = img.SubImage(image.Rect(0, 0, width, height))
+		return fill.Image(fillPreviewLength, fillPreviewLength), nil
+	case backgroundColor != "":
+		c, err := util.ParseColor(backgroundColor)
+		if err != nil {
+			log.Println(backgroundColor, err)
+			return nil, err
+		}
+		return (&util.Fill{Kind: util.FillSolid, Colors: []color.NRGBA{c}}).Image(fillPreviewLength, fillPreviewLength), nil
 	}
+	white := &util.Fill{Kind: util.FillSolid, Colors: []color.NRGBA{{0xff, 0xff, 0xff, 0xff}}}
+	return white.Image(fillPreviewLength, fillPreviewLength), nil
+}
 
-	reader, err = util.OpenURI(foregroundImagePath)
+func loadForeground() (image.Image, error) {
+	reader, err := util.OpenURI(foregroundImagePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer reader.Close()
 	fm, _, err := util.ImageDecode(reader)
+	return fm, err
+}
+
+func loadBackgroundForeground() (bm image.Image, fm image.Image, err error) {
+	if bm, err = loadBackground(); err != nil {
+		return nil, nil, err
+	}
+	if fm, err = loadForeground(); err != nil {
+		return nil, nil, err
+	}
+	return bm, fm, nil
+}
+
+func GenerateLaunchImage() (err error) {
+	bm, fm, err := loadBackgroundForeground()
 	if err != nil {
 		return err
 	}
@@ -378,48 +342,276 @@ func GenerateLaunchImage() (err error) {
 }
 
 func GenerateAppIcon(origin string) error {
-	reader, err := util.OpenURI(origin)
+	data, m, err := readIconSource(origin)
 	if err != nil {
 		log.Println(origin, err)
 		return err
 	}
-	defer reader.Close()
-	m, _, err := util.ImageDecode(reader)
+	if err = writeIOSAppIcon(origin, data, m, path.Join(outputPath, "appicon", "ios", "Images.xcassets")); err != nil {
+		return err
+	}
+	if alternateIconsPath != "" {
+		return GenerateAlternateAppIcons(alternateIconsPath, legacyAlternateIcons, false)
+	}
+	return nil
+}
+
+// writeIOSAppIcon validates the source read from origin and writes AppIcon.appiconset into the asset catalog at assetsDir.
+func writeIOSAppIcon(origin string, data []byte, m image.Image, assetsDir string) error {
+	for _, issue := range lintIconSource(data, m, "ios") {
+		log.Println("warning:", origin, issue)
+	}
+
+	bm, err := prepareAppIcon(m)
 	if err != nil {
 		log.Println(origin, err)
 		return err
 	}
 
-	origLength := m.Bounds().Dx()
-	length := origLength * 4 / 5
-	m = resize.Resize(uint(length), uint(length), m, resize.Bilinear)
+	dir := path.Join(assetsDir, "AppIcon.appiconset")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeAppIconSet(bm, dir, appIconSpecifications, map[string]interface{}{"pre-rendered": true})
+}
 
-	bm := image.NewRGBA(image.Rect(0, 0, origLength, origLength))
-	draw.Draw(bm, bm.Bounds(), &image.Uniform{color.White}, image.ZP, draw.Src)
-	draw.Draw(bm, image.Rect(origLength/10, origLength/10, origLength/10+length, origLength/10+length), m, image.Point{0, 0}, draw.Over)
-
-	os.MkdirAll(path.Join(outputPath, "appicon", "ios", "Images.xcassets", "AppIcon.appiconset"), 0755)
-	for _, spec := range appIconSpecifications {
-		im := resize.Resize(uint(spec.Length), uint(spec.Length), bm, resize.Bilinear)
-		fn := path.Join(outputPath, "appicon", "ios", "Images.xcassets", "AppIcon.appiconset", spec.Name)
-		if err := util.SaveImage(im, fn, util.IT_png); err != nil {
-			log.Println(fn, err)
+// writeAppIconSet resizes m for every spec, writes the images and the matching Contents.json into dir,
+// and fails if any file referenced by Contents.json was not produced.
+func writeAppIconSet(m image.Image, dir string, specs []appIconSpec, properties map[string]interface{}) error {
+	contents := assetContents{
+		Info:       xcodeAssetInfo,
+		Properties: properties,
+	}
+	// a file left by a failed encode or a previous run must not pass the check, so only successful saves count
+	attempted := make(map[string]bool)
+	produced := make(map[string]bool)
+	for _, spec := range specs {
+		contents.Images = append(contents.Images, spec.assetImage())
+		if attempted[spec.Filename] {
 			continue
 		}
-		if err = util.DoCrush(compress, fn); err != nil {
+		attempted[spec.Filename] = true
+
+		im := resize.Resize(uint(spec.Length()), uint(spec.Length()), m, resize.Bilinear)
+		fn := path.Join(dir, spec.Filename)
+		if err := saveAndCrush(im, fn); err != nil {
 			log.Println(fn, err)
+			continue
 		}
+		produced[spec.Filename] = true
 	}
 
-	fd, err := os.OpenFile(filepath.Join(outputPath, "appicon", "ios", "Images.xcassets", "AppIcon.appiconset", "Contents.json"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err := writeContentsJson(dir, contents); err != nil {
+		return err
+	}
+	var missing []string
+	for _, entry := range contents.Images {
+		if !produced[entry.Filename] {
+			missing = append(missing, entry.Filename)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Contents.json in %s references files that were not produced: %s", dir, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// prepareAppIcon squares m and insets it onto the configured background, the result is refused when
+// it would still be transparent since the App Store rejects a marketing icon with alpha.
+func prepareAppIcon(m image.Image) (image.Image, error) {
+	m = squareAppIcon(m)
+	bg, err := appIconBackground(m.Bounds().Dx())
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
+	}
+	bm := insetAppIcon(m, bg)
+	if util.HasTransparency(bm) {
+		return nil, errors.New("the app icon contains transparency, which the App Store rejects, set --matte to flatten it")
+	}
+	return bm, nil
+}
+
+// appIconBackground renders the matte fill and the optional background image at length*length,
+// nil means the background stays transparent.
+func appIconBackground(length int) (image.Image, error) {
+	var bg *image.RGBA
+	if matte != "" && matte != "none" {
+		fill, err := util.ParseFill(matte)
+		if err != nil {
+			log.Println(matte, err)
+			return nil, err
+		}
+		bg = fill.Render(length, length)
+	}
+	if appIconBackgroundPath == "" {
+		if bg == nil {
+			return nil, nil
+		}
+		return bg, nil
+	}
+
+	reader, err := util.OpenURI(appIconBackgroundPath)
+	if err != nil {
+		log.Println(appIconBackgroundPath, err)
+		return nil, err
+	}
+	defer reader.Close()
+	im, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(appIconBackgroundPath, err)
+		return nil, err
+	}
+	if bg == nil {
+		bg = image.NewRGBA(image.Rect(0, 0, length, length))
+	}
+	draw.Draw(bg, bg.Bounds(), util.Cover(im, length, length), image.ZP, draw.Over)
+	return bg, nil
+}
+
+// squareAppIcon letterboxes a non-square m onto a transparent square canvas, or crops its center when letterbox is off.
+func squareAppIcon(m image.Image) image.Image {
+	sz := m.Bounds().Size()
+	if sz.X == sz.Y {
+		return m
+	}
+	if !letterbox {
+		length := sz.X
+		if sz.Y < length {
+			length = sz.Y
+		}
+		return util.Cover(m, length, length)
+	}
+	length := sz.X
+	if sz.Y > length {
+		length = sz.Y
+	}
+	return util.Center(util.Fit(m, length, length), length, length)
+}
+
+// insetAppIcon leaves appIconInset percent of the edge empty on every side of the square m, rounds its corners
+// by appIconCornerRadius percent and draws it over bg, which may be nil for a transparent canvas.
+func insetAppIcon(m image.Image, bg image.Image) *image.RGBA {
+	origLength := m.Bounds().Dx()
+	bm := image.NewRGBA(image.Rect(0, 0, origLength, origLength))
+	if bg != nil {
+		draw.Draw(bm, bm.Bounds(), bg, image.ZP, draw.Src)
+	}
+
+	offset := origLength * int(appIconInset) / 100
+	length := origLength - 2*offset
+	if length != origLength {
+		m = resize.Resize(uint(length), uint(length), m, resize.Bilinear)
+	}
+	rect := image.Rect(offset, offset, offset+length, offset+length)
+	if appIconCornerRadius > 0 {
+		mask := util.NewRoundedRect(rect, float64(length)*float64(appIconCornerRadius)/100)
+		draw.DrawMask(bm, rect, m, m.Bounds().Min, mask, rect.Min, draw.Over)
+	} else {
+		draw.Draw(bm, rect, m, m.Bounds().Min, draw.Over)
+	}
+	return bm
+}
+
+// GenerateModernAppIcon writes the single-size AppIcon set used by Xcode 14+, with the dark and tinted appearances of iOS 18.
+func GenerateModernAppIcon(origin string) error {
+	data, m, err := readIconSource(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	for _, issue := range lintIconSource(data, m, "ios") {
+		log.Println("warning:", origin, issue)
+	}
+
+	light, err := prepareAppIcon(m)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	light = resize.Resize(modernAppIconLength, modernAppIconLength, light, resize.Bilinear)
+
+	var dark image.Image
+	if darkIconPath != "" {
+		if dark, err = loadSquareIcon(darkIconPath, modernAppIconLength); err != nil {
+			return err
+		}
+	} else {
+		// the system draws its own dark gradient behind a transparent icon, only the matte around the artwork is removed
+		dark = util.RemoveMatte(m, darkMatteTolerance)
+		dark = resize.Resize(modernAppIconLength, modernAppIconLength, insetAppIcon(squareAppIcon(dark), nil), resize.Bilinear)
+	}
+
+	var tinted image.Image
+	if tintedIconPath != "" {
+		if tinted, err = loadSquareIcon(tintedIconPath, modernAppIconLength); err != nil {
+			return err
+		}
+	} else {
+		tinted = util.Grayscale(dark)
+	}
+
+	dir := path.Join(outputPath, "appicon", "ios", "Images.xcassets", "AppIcon.appiconset")
+	if err = writeSingleSizeAppIconSet(dir, []appIconVariant{
+		{"", "AppIcon.png", light},
+		{"dark", "AppIcon-dark.png", dark},
+		{"tinted", "AppIcon-tinted.png", tinted},
+	}); err != nil {
+		return err
+	}
+	if alternateIconsPath != "" {
+		return GenerateAlternateAppIcons(alternateIconsPath, legacyAlternateIcons, true)
 	}
-	fd.WriteString(contentsJson)
-	fd.Close()
 	return nil
 }
 
+type appIconVariant struct {
+	appearance string
+	filename   string
+	image      image.Image
+}
+
+// writeSingleSizeAppIconSet writes a universal 1024pt icon set into dir with one entry per appearance,
+// the variant images are expected at modernAppIconLength already.
+func writeSingleSizeAppIconSet(dir string, variants []appIconVariant) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	contents := assetContents{Info: xcodeAssetInfo}
+	for _, variant := range variants {
+		fn := path.Join(dir, variant.filename)
+		if err := saveAndCrush(variant.image, fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+		entry := assetImage{
+			Filename: variant.filename,
+			Idiom:    "universal",
+			Platform: "ios",
+			Size:     fmt.Sprintf("%dx%d", modernAppIconLength, modernAppIconLength),
+		}
+		if variant.appearance != "" {
+			entry.Appearances = []assetAppearance{{Appearance: "luminosity", Value: variant.appearance}}
+		}
+		contents.Images = append(contents.Images, entry)
+	}
+	return writeContentsJson(dir, contents)
+}
+
+func loadSquareIcon(origin string, length uint) (image.Image, error) {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return nil, err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return nil, err
+	}
+	return resize.Resize(length, length, m, resize.Bilinear), nil
+}
+
 func iconScale(inputFile string, outputDir string) error {
 	if b, e := fsutil.DirExists(outputDir); e != nil || !b {
 		if e = os.MkdirAll(outputDir, 0755); e != nil {
@@ -498,119 +690,91 @@ func iconScale(inputFile string, outputDir string) error {
 	return nil
 }
 
-func iOSScale(origin string, templateSize string) error {
-	switch templateSize {
-	case "1x":
-		reader, err := util.OpenURI(origin)
-		if err != nil {
-			return err
-		}
-		defer reader.Close()
-		m, _, err := util.ImageDecode(reader)
-		if err != nil {
-			return err
-		}
-
-		name := filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))]+"@2x"+filepath.Ext(origin))
-		im := resize.Resize(uint(m.Bounds().Size().X*2), uint(m.Bounds().Size().Y*2), m, resize.Bilinear)
-		if err := util.SaveImage(im, name, util.IT_png); err != nil {
-			return err
-		}
-		if err = util.DoCrush(compress, name); err != nil {
-			return err
-		}
-
-		name = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))]+"@3x"+filepath.Ext(origin))
-		im = resize.Resize(uint(m.Bounds().Size().X*3), uint(m.Bounds().Size().Y*3), m, resize.Bilinear)
-		if err := util.SaveImage(im, name, util.IT_png); err != nil {
-			return err
-		}
-		if err = util.DoCrush(compress, name); err != nil {
-			return err
-		}
-	case "2x":
-		var one, two, three string
-		if !strings.HasSuffix(filepath.Base(origin), "@2x") {
-			one = origin
-			two = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))]+"@2x"+filepath.Ext(origin))
-			three = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))]+"@3x"+filepath.Ext(origin))
-			if err := os.Rename(origin, two); err != nil {
-				return err
-			}
-		} else {
-			one = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))-3]+filepath.Ext(origin))
-			two = origin
-			three = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))-3]+"@3x"+filepath.Ext(origin))
-		}
+// iOSScaleImages writes every 1x/2x/3x master of templateSize in origins as a complete Name.imageset under outputDir.
+// A master with an explicit @<n>x suffix wins over the unsuffixed file of the same name, other name conflicts are errors.
+func iOSScaleImages(origins []string, templateSize string, outputDir string) error {
+	scale, err := strconv.Atoi(strings.TrimSuffix(templateSize, "x"))
+	if err != nil || scale < 1 || scale > 3 {
+		return errors.New("unrecognized template size " + templateSize)
+	}
 
-		reader, err := util.OpenURI(two)
-		if err != nil {
-			return err
+	type master struct {
+		origin   string
+		suffixed bool
+	}
+	masters := make(map[string]master)
+	var names []string
+	for _, origin := range origins {
+		name, suffixed, ok := iOSScaleName(origin, scale)
+		if !ok {
+			log.Println("skip", origin, "which is not a", templateSize, "image")
+			continue
 		}
-		defer reader.Close()
-		m, _, err := util.ImageDecode(reader)
-		if err != nil {
-			return err
+		prev, found := masters[name]
+		switch {
+		case !found:
+			names = append(names, name)
+		case prev.suffixed == suffixed:
+			return fmt.Errorf("both %s and %s would write %s.imageset", prev.origin, origin, name)
+		case prev.suffixed:
+			log.Println("skip", origin, "in favor of", prev.origin)
+			continue
+		default:
+			log.Println("skip", prev.origin, "in favor of", origin)
 		}
+		masters[name] = master{origin, suffixed}
+	}
 
-		im := resize.Resize(uint(m.Bounds().Size().X/2), uint(m.Bounds().Size().Y/2), m, resize.Bilinear)
-		if err := util.SaveImage(im, one, util.IT_png); err != nil {
-			return err
-		}
-		if err = util.DoCrush(compress, one); err != nil {
-			return err
+	var failed []string
+	for _, name := range names {
+		origin := masters[name].origin
+		if err = iOSScale(origin, name, scale, outputDir); err != nil {
+			log.Println(origin, err)
+			failed = append(failed, origin)
 		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to scale %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
 
-		im = resize.Resize(uint(m.Bounds().Size().X*3/2), uint(m.Bounds().Size().Y*3/2), m, resize.Bilinear)
-		if err := util.SaveImage(im, three, util.IT_png); err != nil {
-			return err
-		}
-		if err = util.DoCrush(compress, three); err != nil {
-			return err
-		}
-	case "3x":
-		var one, two, three string
-		if !strings.HasSuffix(filepath.Base(origin), "@3x") {
-			one = origin
-			two = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))]+"@2x"+filepath.Ext(origin))
-			three = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))]+"@3x"+filepath.Ext(origin))
-			if err := os.Rename(origin, three); err != nil {
-				return err
+// iOSScaleName returns the imageset name of origin and whether it carries an @<n>x suffix,
+// ok is false when the suffix names another scale.
+func iOSScaleName(origin string, scale int) (name string, suffixed bool, ok bool) {
+	name = filepath.Base(origin)
+	name = name[:len(name)-len(filepath.Ext(name))]
+	for s := 1; s <= 3; s++ {
+		suffix := fmt.Sprintf("@%dx", s)
+		if strings.HasSuffix(name, suffix) {
+			if s != scale {
+				return "", false, false
 			}
-		} else {
-			one = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))-3]+filepath.Ext(origin))
-			two = filepath.Join(filepath.Dir(origin), filepath.Base(origin)[:len(filepath.Base(origin))-len(filepath.Ext(origin))-3]+"@2x"+filepath.Ext(origin))
-			three = origin
-		}
-
-		reader, err := util.OpenURI(three)
-		if err != nil {
-			return err
-		}
-		defer reader.Close()
-		m, _, err := util.ImageDecode(reader)
-		if err != nil {
-			return err
-		}
-
-		im := resize.Resize(uint(m.Bounds().Size().X/3), uint(m.Bounds().Size().Y/3), m, resize.Bilinear)
-		if err := util.SaveImage(im, one, util.IT_png); err != nil {
-			return err
-		}
-		if err = util.DoCrush(compress, one); err != nil {
-			return err
+			return strings.TrimSuffix(name, suffix), true, true
 		}
+	}
+	return name, false, true
+}
 
-		im = resize.Resize(uint(m.Bounds().Size().X*2/3), uint(m.Bounds().Size().Y*2/3), m, resize.Bilinear)
-		if err := util.SaveImage(im, two, util.IT_png); err != nil {
-			return err
-		}
-		if err = util.DoCrush(compress, two); err != nil {
-			return err
-		}
-	default:
-		log.Fatal("unrecognized template size")
+// iOSScale writes origin, a master at scale, as a complete Name.imageset under outputDir. The input is never modified.
+func iOSScale(origin string, name string, scale int, outputDir string) error {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	sz := m.Bounds().Size()
+	if sz.X%scale != 0 || sz.Y%scale != 0 {
+		log.Printf("warning: %s is %dx%d, which is not a multiple of %d\n", origin, sz.X, sz.Y, scale)
+	}
+	if err = os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	log.Println("generating", name+".imageset", "from", origin)
+	return writeImageSet(m, outputDir, name, (sz.X+scale/2)/scale, (sz.Y+scale/2)/scale)
 }
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/missdeer/yairc/util"
+)
+
+const (
+	// the 1x foreground is half of the widest iPad Pro in points, so it never needs to be upscaled on device
+	launchScreenImagePoints = 512
+	// the 1x background covers the longest iPad Pro edge in points
+	launchScreenBackgroundPoints = 1366
+)
+
+var (
+	launchScreenStoryboard = template.Must(template.New("LaunchScreen.storyboard").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<document type="com.apple.InterfaceBuilder3.CocoaTouch.Storyboard.XIB" version="3.0" toolsVersion="21701" targetRuntime="iOS.CocoaTouch" propertyAccessControl="none" useAutolayout="YES" launchScreen="YES" useTraitCollections="YES" useSafeAreas="YES" colorMatched="YES" initialViewController="01J-lp-oVM">
+    <device id="retina6_12" orientation="portrait" appearance="light"/>
+    <dependencies>
+        <plugIn identifier="com.apple.InterfaceBuilder.IBCocoaTouchPlugin" version="21679"/>
+        <capability name="Named colors" minToolsVersion="9.0"/>
+        <capability name="documents saved in the Xcode 8 format" minToolsVersion="8.0"/>
+    </dependencies>
+    <scenes>
+        <!--View Controller-->
+        <scene sceneID="EHf-IW-A2E">
+            <objects>
+                <viewController id="01J-lp-oVM" sceneMemberID="viewController">
+                    <view key="view" contentMode="scaleToFill" id="Ze5-6b-2t3">
+                        <rect key="frame" x="0.0" y="0.0" width="393" height="852"/>
+                        <autoresizingMask key="autoresizingMask" widthSizable="YES" heightSizable="YES"/>
+                        <subviews>
+{{- if .BackgroundImage}}
+                            <imageView clipsSubviews="YES" userInteractionEnabled="NO" contentMode="scaleAspectFill" image="LaunchBackground" translatesAutoresizingMaskIntoConstraints="NO" id="bgI-mV-0Lw">
+                                <rect key="frame" x="0.0" y="0.0" width="393" height="852"/>
+                            </imageView>
+{{- end}}
+                            <imageView clipsSubviews="YES" userInteractionEnabled="NO" contentMode="scaleAspectFit" image="LaunchImage" translatesAutoresizingMaskIntoConstraints="NO" id="fgI-mV-1Lw">
+                                <rect key="frame" x="98" y="{{.FrameY}}" width="196.5" height="{{.FrameHeight}}"/>
+                            </imageView>
+                        </subviews>
+{{- if not .BackgroundImage}}
+                        <color key="backgroundColor" name="LaunchBackground"/>
+{{- end}}
+                        <constraints>
+{{- if .BackgroundImage}}
+                            <constraint firstItem="bgI-mV-0Lw" firstAttribute="top" secondItem="Ze5-6b-2t3" secondAttribute="top" id="bgC-tp-0Ct"/>
+                            <constraint firstItem="bgI-mV-0Lw" firstAttribute="bottom" secondItem="Ze5-6b-2t3" secondAttribute="bottom" id="bgC-bt-1Ct"/>
+                            <constraint firstItem="bgI-mV-0Lw" firstAttribute="leading" secondItem="Ze5-6b-2t3" secondAttribute="leading" id="bgC-ld-2Ct"/>
+                            <constraint firstItem="bgI-mV-0Lw" firstAttribute="trailing" secondItem="Ze5-6b-2t3" secondAttribute="trailing" id="bgC-tr-3Ct"/>
+{{- end}}
+                            <constraint firstItem="fgI-mV-1Lw" firstAttribute="centerX" secondItem="Ze5-6b-2t3" secondAttribute="centerX" id="fgC-cx-0Ct"/>
+                            <constraint firstItem="fgI-mV-1Lw" firstAttribute="centerY" secondItem="Ze5-6b-2t3" secondAttribute="centerY" id="fgC-cy-1Ct"/>
+                            <constraint firstItem="fgI-mV-1Lw" firstAttribute="width" secondItem="Ze5-6b-2t3" secondAttribute="width" multiplier="0.5" priority="750" id="fgC-wd-2Ct"/>
+                            <constraint firstItem="fgI-mV-1Lw" firstAttribute="width" relation="lessThanOrEqual" secondItem="Ze5-6b-2t3" secondAttribute="height" multiplier="0.5" id="fgC-wh-3Ct"/>
+                            <constraint firstItem="fgI-mV-1Lw" firstAttribute="width" secondItem="fgI-mV-1Lw" secondAttribute="height" multiplier="{{.Width}}:{{.Height}}" id="fgC-ar-4Ct"/>
+                        </constraints>
+                    </view>
+                </viewController>
+                <placeholder placeholderIdentifier="IBFirstResponder" id="iYj-Kq-Ea1" userLabel="First Responder" sceneMemberID="firstResponder"/>
+            </objects>
+            <point key="canvasLocation" x="53" y="375"/>
+        </scene>
+    </scenes>
+    <resources>
+        <image name="LaunchImage" width="{{.Width}}" height="{{.Height}}"/>
+{{- if .BackgroundImage}}
+        <image name="LaunchBackground" width="{{.BackgroundWidth}}" height="{{.BackgroundHeight}}"/>
+{{- else}}
+        <namedColor name="LaunchBackground">
+            <color red="{{.Red}}" green="{{.Green}}" blue="{{.Blue}}" alpha="{{.Alpha}}" colorSpace="custom" customColorSpace="sRGB"/>
+        </namedColor>
+{{- end}}
+    </resources>
+</document>
+`))
+)
+
+type launchScreenParams struct {
+	Width            int
+	Height           int
+	FrameY           float64
+	FrameHeight      float64
+	BackgroundImage  bool
+	BackgroundWidth  int
+	BackgroundHeight int
+	Red              float64
+	Green            float64
+	Blue             float64
+	Alpha            float64
+}
+
+// GenerateLaunchScreen writes a LaunchScreen.storyboard with the foreground centered on the background,
+// together with the asset catalog entries it references.
+func GenerateLaunchScreen() error {
+	reader, err := util.OpenURI(foregroundImagePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	fm, _, err := util.ImageDecode(reader)
+	if err != nil {
+		return err
+	}
+
+	dir := path.Join(outputPath, "launchimage", "ios")
+	assetsDir := path.Join(dir, "Images.xcassets")
+	if err = os.MkdirAll(assetsDir, 0755); err != nil {
+		return err
+	}
+
+	sz := fm.Bounds().Size()
+	params := launchScreenParams{
+		Width:  launchScreenImagePoints,
+		Height: launchScreenImagePoints * sz.Y / sz.X,
+	}
+	params.FrameHeight = 196.5 * float64(params.Height) / float64(params.Width)
+	params.FrameY = (852 - params.FrameHeight) / 2
+	log.Println("generating LaunchImage.imageset")
+	if err = writeImageSet(fm, assetsDir, "LaunchImage", params.Width, params.Height); err != nil {
+		return err
+	}
+
+	if backgroundGradient != "" || backgroundImagePath != "" {
+		bm, err := loadBackground()
+		if err != nil {
+			return err
+		}
+		bs := bm.Bounds().Size()
+		params.BackgroundImage = true
+		if _, ok := bm.(*util.FillImage); ok {
+			// gradients are rendered for the portrait iPad Pro, the largest screen in points
+			params.BackgroundWidth, params.BackgroundHeight = launchScreenBackgroundPoints*3/4, launchScreenBackgroundPoints
+		} else if bs.X > bs.Y {
+			params.BackgroundWidth, params.BackgroundHeight = launchScreenBackgroundPoints, launchScreenBackgroundPoints*bs.Y/bs.X
+		} else {
+			params.BackgroundWidth, params.BackgroundHeight = launchScreenBackgroundPoints*bs.X/bs.Y, launchScreenBackgroundPoints
+		}
+		log.Println("generating LaunchBackground.imageset")
+		if err = writeImageSet(bm, assetsDir, "LaunchBackground", params.BackgroundWidth, params.BackgroundHeight); err != nil {
+			return err
+		}
+	} else {
+		bc := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+		if backgroundColor != "" {
+			if bc, err = util.ParseColor(backgroundColor); err != nil {
+				log.Println(backgroundColor, err)
+				return err
+			}
+		}
+		params.Red, params.Green, params.Blue, params.Alpha = float64(bc.R)/255, float64(bc.G)/255, float64(bc.B)/255, float64(bc.A)/255
+		log.Println("generating LaunchBackground.colorset")
+		if err = writeColorSet(bc, assetsDir, "LaunchBackground"); err != nil {
+			return err
+		}
+	}
+
+	fd, err := os.OpenFile(path.Join(dir, "LaunchScreen.storyboard"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return launchScreenStoryboard.Execute(fd, params)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/missdeer/yairc/util"
+)
+
+const (
+	// App Store marketing icon and Play Store listing icon sizes
+	iosIconSourceLength     = 1024
+	androidIconSourceLength = 512
+
+	// per channel distances, in 8-bit units, used to compare corner and edge samples
+	cornerSimilarDistance   = 24
+	cornerDifferentDistance = 48
+)
+
+// readIconSource reads the raw bytes of the image at uri and decodes them, the bytes are kept
+// so that the PNG chunks can be inspected as well.
+func readIconSource(uri string) ([]byte, image.Image, error) {
+	reader, err := util.OpenURI(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, _, err := util.ImageDecode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, m, nil
+}
+
+// lintIconSource returns the problems found in an ios or android app icon source.
+func lintIconSource(data []byte, m image.Image, platform string) (issues []string) {
+	sz := m.Bounds().Size()
+	if sz.X != sz.Y {
+		if letterbox {
+			issues = append(issues, fmt.Sprintf("not square (%dx%d), it will be letterboxed", sz.X, sz.Y))
+		} else {
+			issues = append(issues, fmt.Sprintf("not square (%dx%d), it will be cropped", sz.X, sz.Y))
+		}
+	}
+
+	minLength := iosIconSourceLength
+	if platform == "android" {
+		minLength = androidIconSourceLength
+	}
+	if sz.X < minLength || sz.Y < minLength {
+		issues = append(issues, fmt.Sprintf("smaller than %dx%d, it will be upscaled", minLength, minLength))
+	}
+
+	if platform == "ios" && util.HasTransparency(m) {
+		issues = append(issues, "contains transparency, which is not allowed in the App Store marketing icon")
+	}
+
+	issues = append(issues, lintColorProfile(data, m)...)
+
+	if issue := lintCorners(m); issue != "" {
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// lintColorProfile reports sources that are not plain 8-bit sRGB.
+func lintColorProfile(data []byte, m image.Image) (issues []string) {
+	switch m.(type) {
+	case *image.CMYK:
+		issues = append(issues, "uses CMYK color, convert it to sRGB")
+	case *image.RGBA64, *image.NRGBA64, *image.Gray16:
+		issues = append(issues, "uses 16 bits per channel, 8 bits are expected")
+	}
+
+	info, err := util.ReadPNGInfo(bytes.NewReader(data))
+	if err != nil {
+		// not a PNG, nothing more to inspect
+		return issues
+	}
+	if info.ICCProfile != "" && !strings.Contains(strings.ToLower(info.ICCProfile), "srgb") {
+		issues = append(issues, fmt.Sprintf("embeds the ICC profile %q, colors may shift when rendered as sRGB", info.ICCProfile))
+	}
+	if info.HasChunk("gAMA") && !info.HasChunk("sRGB") && !info.HasChunk("iCCP") {
+		issues = append(issues, "has a gAMA chunk without sRGB, colors may be rendered differently across devices")
+	}
+	return issues
+}
+
+// lintCorners looks for pre-rounded corners: both stores apply their own mask, so the corners of
+// the source should continue the edges instead of being transparent or filled with a matte.
+func lintCorners(m image.Image) string {
+	rc := m.Bounds()
+	if rc.Dx() < 3 || rc.Dy() < 3 {
+		return ""
+	}
+	corners := []color.Color{
+		m.At(rc.Min.X+1, rc.Min.Y+1),
+		m.At(rc.Max.X-2, rc.Min.Y+1),
+		m.At(rc.Min.X+1, rc.Max.Y-2),
+		m.At(rc.Max.X-2, rc.Max.Y-2),
+	}
+	cx, cy := (rc.Min.X+rc.Max.X)/2, (rc.Min.Y+rc.Max.Y)/2
+	edges := []color.Color{
+		m.At(cx, rc.Min.Y+1),
+		m.At(cx, rc.Max.Y-2),
+		m.At(rc.Min.X+1, cy),
+		m.At(rc.Max.X-2, cy),
+	}
+
+	edgesOpaque := true
+	for _, c := range edges {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			edgesOpaque = false
+		}
+	}
+	if edgesOpaque {
+		for _, c := range corners {
+			if _, _, _, a := c.RGBA(); a != 0xffff {
+				return "has transparent corners while the edges are opaque, it looks pre-rounded"
+			}
+		}
+	}
+
+	for _, c := range corners[1:] {
+		if colorDistance(c, corners[0]) > cornerSimilarDistance {
+			return ""
+		}
+	}
+	for _, e := range edges {
+		if colorDistance(corners[0], e) <= cornerDifferentDistance {
+			return ""
+		}
+	}
+	return "has corners that differ from all edges, it looks pre-rounded or framed"
+}
+
+// colorDistance returns the largest per channel difference of c1 and c2, in 8-bit units.
+func colorDistance(c1, c2 color.Color) uint32 {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	var d uint32
+	for _, p := range [][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}, {a1, a2}} {
+		diff := p[0] - p[1]
+		if p[0] < p[1] {
+			diff = p[1] - p[0]
+		}
+		if diff>>8 > d {
+			d = diff >> 8
+		}
+	}
+	return d
+}
+
+// LintIcons checks every icon source in uris for the given platform and fails if any problem is found.
+func LintIcons(uris []string, platform string) error {
+	count := 0
+	for _, uri := range uris {
+		data, m, err := readIconSource(uri)
+		if err != nil {
+			log.Println(uri, err)
+			count++
+			continue
+		}
+		issues := lintIconSource(data, m, platform)
+		for _, issue := range issues {
+			fmt.Printf("%s: %s\n", uri, issue)
+		}
+		count += len(issues)
+	}
+	if count > 0 {
+		return fmt.Errorf("%d icon issue(s) found", count)
+	}
+	return nil
+}
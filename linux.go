@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/missdeer/yairc/util"
+	"github.com/nfnt/resize"
+)
+
+const (
+	// snapd copies snap/gui into meta/gui and only looks for icon.png or icon.svg there
+	snapIconName = "icon"
+)
+
+var (
+	hicolorSizes = []int{16, 22, 24, 32, 48, 64, 128, 256, 512}
+
+	desktopEntry = template.Must(template.New("desktop").Parse(`[Desktop Entry]
+Type=Application
+Name={{.Name}}
+Exec={{.Exec}}
+Icon={{.Icon}}
+Terminal=false
+Categories=Utility;
+`))
+
+	invalidAppIDChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+type desktopEntryParams struct {
+	Name string
+	Exec string
+	Icon string
+}
+
+// linuxAppID returns the icon and .desktop file name, the app id when given, otherwise derived from the app name.
+func linuxAppID() string {
+	if linuxAppIDFlag != "" {
+		return linuxAppIDFlag
+	}
+	if id := strings.Trim(invalidAppIDChars.ReplaceAllString(strings.ToLower(appName), "-"), "-"); id != "" {
+		return id
+	}
+	return "app"
+}
+
+// GenerateLinuxIcons writes the hicolor icon theme and a template .desktop file in the freedesktop, flatpak or snap layout.
+func GenerateLinuxIcons(origin string) error {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	m = squareAppIcon(m)
+
+	id := linuxAppID()
+	name := appName
+	if name == "" {
+		name = id
+	}
+	dir := path.Join(outputPath, "appicon", "linux")
+
+	switch linuxLayout {
+	case "freedesktop":
+		return writeHicolorTheme(m, path.Join(dir, "share"), id, desktopEntryParams{name, id, id})
+	case "flatpak":
+		// flatpak exports the icons and the .desktop file of /app/share named after the app id
+		return writeHicolorTheme(m, path.Join(dir, "flatpak", "files", "share"), id, desktopEntryParams{name, id, id})
+	case "snap":
+		return writeSnapGui(m, path.Join(dir, "snap", "gui"), id, desktopEntryParams{name, id, "${SNAP}/meta/gui/" + snapIconName + ".png"})
+	}
+	return fmt.Errorf("unsupported linux layout %s", linuxLayout)
+}
+
+// writeHicolorTheme writes shareDir/icons/hicolor/<size>x<size>/apps/<id>.png, the scalable svg when given
+// and shareDir/applications/<id>.desktop.
+func writeHicolorTheme(m image.Image, shareDir string, id string, entry desktopEntryParams) error {
+	for _, size := range hicolorSizes {
+		dir := path.Join(shareDir, "icons", "hicolor", fmt.Sprintf("%dx%d", size, size), "apps")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		fn := path.Join(dir, id+".png")
+		log.Println("generating", fn)
+		if err := saveAndCrush(resize.Resize(uint(size), uint(size), m, resize.Bilinear), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+	}
+
+	if scalableIconPath != "" {
+		dir := path.Join(shareDir, "icons", "hicolor", "scalable", "apps")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := copySVG(scalableIconPath, path.Join(dir, id+".svg")); err != nil {
+			return err
+		}
+	}
+
+	return writeDesktopEntry(path.Join(shareDir, "applications"), id, entry)
+}
+
+// writeSnapGui writes the largest icon, the svg when given and the .desktop file snapcraft picks up from snap/gui.
+func writeSnapGui(m image.Image, dir string, id string, entry desktopEntryParams) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	size := hicolorSizes[len(hicolorSizes)-1]
+	fn := path.Join(dir, snapIconName+".png")
+	log.Println("generating", fn)
+	if err := saveAndCrush(resize.Resize(uint(size), uint(size), m, resize.Bilinear), fn); err != nil {
+		log.Println(fn, err)
+		return err
+	}
+	if scalableIconPath != "" {
+		if err := copySVG(scalableIconPath, path.Join(dir, snapIconName+".svg")); err != nil {
+			return err
+		}
+	}
+	return writeDesktopEntry(dir, id, entry)
+}
+
+// copySVG copies the svg at uri unchanged, nothing is rasterized.
+func copySVG(uri string, fn string) error {
+	reader, err := util.OpenURI(uri)
+	if err != nil {
+		log.Println(uri, err)
+		return err
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		log.Println(uri, err)
+		return err
+	}
+	if !strings.Contains(string(data), "<svg") {
+		return fmt.Errorf("%s is not an svg image", uri)
+	}
+	log.Println("generating", fn)
+	return ioutil.WriteFile(fn, data, 0644)
+}
+
+func writeDesktopEntry(dir string, id string, entry desktopEntryParams) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fn := path.Join(dir, id+".desktop")
+	log.Println("generating", fn)
+	fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return desktopEntry.Execute(fd, entry)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"os"
+	"path"
+
+	"github.com/missdeer/yairc/util"
+)
+
+const (
+	// geometry of Apple's macOS Big Sur app icon template on a 1024x1024 canvas
+	bigSurCanvasLength = 1024
+	bigSurBodyLength   = 824
+	bigSurCornerRadius = 185.4
+	bigSurShadowOffset = 12
+	bigSurShadowRadius = 10
+	bigSurShadowAlpha  = 0x80
+)
+
+var (
+	macAppIconSpecifications = []appIconSpec{
+		{16, "mac", 1, "", "", "icon_16x16.png"},
+		{16, "mac", 2, "", "", "icon_16x16@2x.png"},
+		{32, "mac", 1, "", "", "icon_32x32.png"},
+		{32, "mac", 2, "", "", "icon_32x32@2x.png"},
+		{128, "mac", 1, "", "", "icon_128x128.png"},
+		{128, "mac", 2, "", "", "icon_128x128@2x.png"},
+		{256, "mac", 1, "", "", "icon_256x256.png"},
+		{256, "mac", 2, "", "", "icon_256x256@2x.png"},
+		{512, "mac", 1, "", "", "icon_512x512.png"},
+		{512, "mac", 2, "", "", "icon_512x512@2x.png"},
+	}
+)
+
+// GenerateMacAppIcon writes the macOS AppIcon.appiconset and an AppIcon.icns with every resolution embedded.
+func GenerateMacAppIcon(origin string) error {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	m = squareAppIcon(m)
+	if macTemplate {
+		m = applyBigSurTemplate(m)
+	}
+
+	dir := path.Join(outputPath, "appicon", "macos", "Assets.xcassets", "AppIcon.appiconset")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err = writeAppIconSet(m, dir, macAppIconSpecifications, nil); err != nil {
+		return err
+	}
+
+	fn := path.Join(outputPath, "appicon", "macos", "AppIcon.icns")
+	fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	log.Println("generating", fn)
+	return util.EncodeICNS(fd, m)
+}
+
+// applyBigSurTemplate clips m to the rounded rectangle of the Big Sur icon grid, leaving the template padding around it,
+// and adds the drop shadow.
+func applyBigSurTemplate(m image.Image) image.Image {
+	offset := (bigSurCanvasLength - bigSurBodyLength) / 2
+	body := image.Rect(offset, offset, offset+bigSurBodyLength, offset+bigSurBodyLength)
+	mask := util.NewRoundedRect(body, bigSurCornerRadius)
+
+	shadow := image.NewAlpha(image.Rect(0, 0, bigSurCanvasLength, bigSurCanvasLength))
+	draw.Draw(shadow, body.Add(image.Point{0, bigSurShadowOffset}), mask, body.Min, draw.Src)
+	shadow = util.BlurAlpha(shadow, bigSurShadowRadius)
+
+	canvas := image.NewRGBA(image.Rect(0, 0, bigSurCanvasLength, bigSurCanvasLength))
+	draw.DrawMask(canvas, canvas.Bounds(), &image.Uniform{color.NRGBA{0, 0, 0, bigSurShadowAlpha}}, image.ZP, shadow, image.ZP, draw.Over)
+	draw.DrawMask(canvas, body, util.Cover(m, bigSurBodyLength, bigSurBodyLength), image.ZP, mask, body.Min, draw.Over)
+	return canvas
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"image"
 	"log"
 	"os"
 	"path/filepath"
@@ -16,6 +17,22 @@ var (
 	compress               bool
 	backgroundImagePath    string
 	foregroundImagePath    string
+	brandingImagePath      string
+	darkIconPath           string
+	tintedIconPath         string
+	modernAppIcon          bool
+	storyboardLaunchScreen bool
+	templateSize           = "2x"
+	macTemplate            bool
+	layerImagePaths        []string
+	alternateIconsPath     string
+	legacyAlternateIcons   bool
+	letterbox                   = true
+	matte                       = "#ffffff"
+	appIconInset           uint = 10
+	appIconCornerRadius    uint
+	appIconBackgroundPath  string
+	foregroundLayout       = launchLayout{Size: 0.5, Anchor: "center"}
 	inputPath              string
 	outputPath             string
 	action                 string
@@ -28,6 +45,20 @@ var (
 	outputWidth            uint
 	cutEdgeStep            uint = 1
 	transparentWhiteDirect bool
+	adaptiveIcon           bool
+	backgroundColor        string
+	backgroundGradient     string
+	appName                string
+	cursorHotspot          []int
+	linuxAppIDFlag         string
+	linuxLayout            = "freedesktop"
+	scalableIconPath       string
+	monochromeIcon         bool
+	monochromeThreshold    uint
+	resourceName           = "ic_launcher"
+	baseDensity            = "xxxhdpi"
+	webpOutput             bool
+	formFactors            = []string{"phone"}
 	// Gitcommit contains the commit where we built from.
 	GitCommit string
 
@@ -40,6 +71,7 @@ var (
 		".webp": util.IT_webp,
 		".tiff": util.IT_tiff,
 		".ico":  util.IT_ico,
+		".cur":  util.IT_cur,
 		".bmp":  util.IT_bmp,
 	}
 )
@@ -51,10 +83,44 @@ func main() {
 	flag.Uint32VarP(&green, "green", "", green, "set green threshold")
 	flag.Uint32VarP(&blue, "blue", "", blue, "set blue threshold")
 	flag.BoolVarP(&compress, "compress", "", true, "compress output PNG files")
-	flag.StringVarP(&platform, "platform", "p", "common", "candidates: ios, android, common")
-	flag.StringVarP(&action, "action", "a", "", "candidats: icons, appIcon, launchImage, transparent, invert, resize, convert, cutedge, info")
-	flag.StringVarP(&backgroundImagePath, "background", "b", "", "path of background image for launch image")
+	flag.StringVarP(&platform, "platform", "p", "common", "candidates: ios, android, macos, watchos, tvos, visionos, web, windows, linux, flutter, react-native, capacitor, cordova, electron, qt, common")
+	flag.StringVarP(&action, "action", "a", "", "candidats: icons, drawables, scale, appIcon, launchImage, notificationIcon, playStore, lint, transparent, invert, resize, convert, cutedge, info")
+	flag.StringVarP(&backgroundImagePath, "background", "b", "", "path of background image for launch image, cannot be combined with background-color or background-gradient")
 	flag.StringVarP(&foregroundImagePath, "foreground", "f", "", "path of foreground image for launch image")
+	flag.Float64VarP(&foregroundLayout.Size, "fg-size", "", foregroundLayout.Size, "size of the launch image foreground, as a fraction of the short edge")
+	flag.StringVarP(&foregroundLayout.Anchor, "fg-anchor", "", foregroundLayout.Anchor, "anchor of the launch image foreground inside the safe area, candidates: center, top-third, bottom")
+	flag.Float64VarP(&foregroundLayout.OffsetX, "fg-offset-x", "", 0, "horizontal offset of the launch image foreground, as a fraction of the short edge")
+	flag.Float64VarP(&foregroundLayout.OffsetY, "fg-offset-y", "", 0, "vertical offset of the launch image foreground, as a fraction of the short edge")
+	flag.IntVarP(&foregroundLayout.MaxPixels, "fg-max", "", 0, "maximum pixels of the launch image foreground longer edge, 0 for no limit")
+	flag.StringVarP(&brandingImagePath, "branding", "", "", "path of branding image for android 12 splash screen")
+	flag.BoolVarP(&modernAppIcon, "single-size", "", false, "generate the single-size ios app icon set with dark and tinted appearances")
+	flag.StringVarP(&darkIconPath, "dark", "", "", "path of dark appearance ios app icon, derived from input when omitted")
+	flag.StringVarP(&tintedIconPath, "tinted", "", "", "path of tinted appearance ios app icon, derived from input when omitted")
+	flag.BoolVarP(&storyboardLaunchScreen, "storyboard", "", false, "generate ios LaunchScreen.storyboard with asset catalog instead of launch images")
+	flag.StringVarP(&alternateIconsPath, "alternate-icons", "", "", "directory of ios alternate app icon masters, one icon set per image")
+	flag.BoolVarP(&legacyAlternateIcons, "legacy-alternate-icons", "", false, "write ios alternate icons as loose 60pt @2x/@3x files instead of asset catalog icon sets")
+	flag.BoolVarP(&letterbox, "letterbox", "", letterbox, "letterbox non-square ios app icon sources, false to crop their center")
+	flag.StringVarP(&matte, "matte", "", matte, "background of ios app icons, a color, linear-gradient(...) or radial-gradient(...), none to keep transparency")
+	flag.StringVarP(&appIconBackgroundPath, "icon-background", "", "", "path of background image of ios app icons, drawn over the matte")
+	flag.UintVarP(&appIconInset, "inset", "", appIconInset, "padding on every side of ios app icons, in percent of the icon edge, 0 to fill edge to edge")
+	flag.UintVarP(&appIconCornerRadius, "corner-radius", "", 0, "corner radius of the inset ios app icon, in percent of its edge")
+	flag.StringVarP(&templateSize, "template-size", "", templateSize, "scale of the input images for ios scale action, candidates: 1x, 2x, 3x")
+	flag.BoolVarP(&macTemplate, "macos-template", "", false, "apply the macOS Big Sur rounded-rect template with padding and shadow")
+	flag.StringSliceVarP(&layerImagePaths, "layers", "", nil, "paths of tvOS/visionOS app icon layer images, ordered from front to back")
+	flag.StringVarP(&backgroundColor, "background-color", "", "", "background color, #rrggbb or rgb(r,g,b), cannot be combined with background or background-gradient")
+	flag.StringVarP(&backgroundGradient, "background-gradient", "", "", "launch background gradient, linear-gradient([<angle>deg,] c1, c2, ...) or radial-gradient(c1, c2, ...), cannot be combined with background or background-color")
+	flag.BoolVarP(&adaptiveIcon, "adaptive", "", false, "generate android adaptive icon layers from foreground and background")
+	flag.StringVarP(&appName, "app-name", "", "", "application name written to the web app manifest")
+	flag.StringVarP(&linuxAppIDFlag, "app-id", "", "", "linux icon and .desktop file name, e.g. org.example.App, derived from app-name when omitted")
+	flag.StringVarP(&linuxLayout, "linux-layout", "", linuxLayout, "layout of linux icons, candidates: freedesktop, flatpak, snap")
+	flag.StringVarP(&scalableIconPath, "svg", "", "", "path of scalable svg icon copied unchanged into the linux icon theme")
+	flag.StringVarP(&resourceName, "name", "", resourceName, "android launcher icon resource name")
+	flag.StringVarP(&baseDensity, "base-density", "", baseDensity, "density of the input drawables, candidates: ldpi, mdpi, hdpi, xhdpi, xxhdpi, xxxhdpi")
+	flag.BoolVarP(&webpOutput, "webp", "", false, "write android drawables as lossless WebP instead of PNG")
+	flag.StringSliceVarP(&formFactors, "form-factor", "", formFactors, "android launcher icon form factors, candidates: phone, tv, wear, auto")
+	flag.BoolVarP(&monochromeIcon, "monochrome", "", false, "add android 13 themed icon monochrome layer to adaptive icon")
+	flag.UintVarP(&monochromeThreshold, "monochrome-threshold", "", 0, "luminance threshold (1-255) for monochrome layer, 0 to use the source alpha channel")
+	flag.IntSliceVarP(&cursorHotspot, "hotspot", "", []int{0, 0}, "x,y of the cursor hotspot in pixels of the input image, used when converting to .cur")
 	flag.StringVarP(&inputPath, "input", "i", "", "input image file path")
 	flag.StringVarP(&outputPath, "output", "o", ".", "output directory/file path")
 	flag.StringVarP(&cutEdgePosition, "cut-edge-position", "e", "", "cut edge position, candidates: (l)eft, (r)ight, (t)op, (b)ottom, (h)orizontal, (v)ertical, (a)ll")
@@ -76,6 +142,39 @@ func main() {
 		return
 	}
 
+	if monochromeThreshold > 255 {
+		log.Fatal("monochrome-threshold must be between 0 and 255")
+	}
+	if monochromeIcon && !adaptiveIcon {
+		log.Fatal("monochrome is a layer of adaptive icons, use it together with --adaptive")
+	}
+
+	if foregroundLayout.Size <= 0 || foregroundLayout.Size > 1 {
+		log.Fatal("fg-size must be greater than 0 and not greater than 1")
+	}
+	switch foregroundLayout.Anchor {
+	case "center", "top-third", "bottom":
+	default:
+		log.Fatal("unsupported fg-anchor ", foregroundLayout.Anchor)
+	}
+
+	backgrounds := 0
+	for _, b := range []string{backgroundImagePath, backgroundColor, backgroundGradient} {
+		if b != "" {
+			backgrounds++
+		}
+	}
+	if backgrounds > 1 {
+		log.Fatal("background, background-color and background-gradient cannot be combined, give only one of them")
+	}
+
+	if appIconInset >= 50 {
+		log.Fatal("inset must be less than 50 percent")
+	}
+	if appIconCornerRadius > 50 {
+		log.Fatal("corner-radius must not be greater than 50 percent")
+	}
+
 	// icon scale mode
 	if action == "icons" && inputPath != "" && outputPath != "" {
 		log.Println("generate /@2x/@3x/@4x & /x18/x36/x48 icons from", inputPath, "to", outputPath)
@@ -86,6 +185,16 @@ func main() {
 		return
 	}
 
+	// ios single-size app icon mode
+	if action == "appIcon" && platform == "ios" && modernAppIcon {
+		fmt.Println("output ios single-size app icons")
+		err := GenerateModernAppIcon(inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// ios app icon mode
 	if action == "appIcon" && platform == "ios" {
 		fmt.Println("output ios app icons")
@@ -96,6 +205,16 @@ func main() {
 		return
 	}
 
+	// ios launch screen storyboard mode
+	if action == "launchImage" && platform == "ios" && storyboardLaunchScreen {
+		fmt.Println("output ios launch screen storyboard")
+		err := GenerateLaunchScreen()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// ios launch image mode
 	if action == "launchImage" && platform == "ios" {
 		fmt.Println("output ios launch images")
@@ -106,6 +225,92 @@ func main() {
 		return
 	}
 
+	// windows app icon mode
+	if action == "appIcon" && platform == "windows" {
+		fmt.Println("output windows app icons")
+		err := GenerateWindowsAppIcon(inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// linux icon theme mode
+	if action == "appIcon" && platform == "linux" {
+		fmt.Println("output linux icons")
+		err := GenerateLinuxIcons(inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// cross-platform framework preset mode
+	if action == "appIcon" && (platform == "flutter" || platform == "react-native" || platform == "capacitor" ||
+		platform == "cordova" || platform == "electron" || platform == "qt") {
+		fmt.Println("output", platform, "app icons")
+		err := GenerateFrameworkIcons(inputPath, platform)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// web favicon and manifest mode
+	if action == "appIcon" && platform == "web" {
+		fmt.Println("output web icons")
+		err := GenerateWebIcons(inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if action == "appIcon" && platform == "macos" {
+		fmt.Println("output macos app icons")
+		err := GenerateMacAppIcon(inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if action == "appIcon" && platform == "watchos" {
+		fmt.Println("output watchos app icons")
+		err := GenerateWatchAppIcon(inputPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if action == "appIcon" && platform == "tvos" {
+		fmt.Println("output tvos app icon and top shelf images")
+		err := GenerateTVAppIcon()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if action == "appIcon" && platform == "visionos" {
+		fmt.Println("output visionos app icon")
+		err := GenerateVisionAppIcon()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if action == "appIcon" && platform == "android" && adaptiveIcon {
+		fmt.Println("output android adaptive launcher icons")
+		err := GenerateAdaptiveIcon()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if action == "appIcon" && platform == "android" {
 		fmt.Println("output android launcher icons")
 		err := GenerateLauncherIcon(inputPath)
@@ -115,9 +320,27 @@ func main() {
 		return
 	}
 
-	if action == "launchImage" && platform == "ios" {
+	if action == "launchImage" && platform == "android" {
 		fmt.Println("output android splash screen images")
-		err := GenerateSplashScreen(inputPath)
+		err := GenerateSplashScreen()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if action == "playStore" && platform == "android" {
+		fmt.Println("output google play store listing assets")
+		err := GeneratePlayStoreAssets()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if action == "notificationIcon" && platform == "android" && inputPath != "" {
+		fmt.Println("output android notification icons")
+		err := GenerateNotificationIcon(inputPath)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -147,7 +370,15 @@ func main() {
 			log.Fatal("decoding source image failed", err)
 		}
 		outExt := filepath.Ext(outputPath)
-		if it, ok := imageFormatMap[strings.ToLower(outExt)]; ok {
+		if it, ok := imageFormatMap[strings.ToLower(outExt)]; ok && it == util.IT_cur {
+			if len(cursorHotspot) != 2 {
+				log.Fatal("hotspot must be given as x,y")
+			}
+			if sz := srcImg.Bounds().Size(); cursorHotspot[0] < 0 || cursorHotspot[0] >= sz.X || cursorHotspot[1] < 0 || cursorHotspot[1] >= sz.Y {
+				log.Fatalf("hotspot %d,%d is outside the %dx%d image", cursorHotspot[0], cursorHotspot[1], sz.X, sz.Y)
+			}
+			err = util.SaveCursor(srcImg, outputPath, image.Point{cursorHotspot[0], cursorHotspot[1]})
+		} else if ok {
 			err = util.SaveImage(srcImg, outputPath, it)
 		} else {
 			log.Fatal("unsupported target image format")
@@ -285,6 +516,45 @@ func main() {
 		}
 	}
 
+	if action == "drawables" && platform == "android" && len(args) > 0 {
+		log.Println("generate drawable-ldpi ~ drawable-xxxhdpi drawables from", baseDensity, "to", outputPath)
+		for _, uri := range args {
+			if err := GenerateDrawables(uri); err != nil {
+				log.Println(err)
+			}
+		}
+		return
+	}
+
+	if action == "scale" && platform == "ios" && len(args) > 0 {
+		log.Println("generate 1x/2x/3x imagesets from", templateSize, "images to", outputPath)
+		if err := iOSScaleImages(expandImageArgs(args), templateSize, outputPath); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// icon source lint mode
+	if action == "lint" {
+		switch platform {
+		case "common":
+			// the ios rules are the stricter ones
+			platform = "ios"
+		case "ios", "android":
+		default:
+			log.Fatal("lint supports the ios and android platforms, not ", platform)
+		}
+		// args already holds --input
+		uris := expandImageArgs(args)
+		if len(uris) == 0 {
+			log.Fatal("no icon to lint, pass it with --input or as arguments")
+		}
+		if err := LintIcons(uris, platform); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if action == "info" && len(args) > 0 {
 		log.Println("info color")
 		for _, uri := range args {
@@ -303,3 +573,31 @@ func main() {
 		return
 	}
 }
+
+func saveAndCrush(m image.Image, fn string) error {
+	if err := util.SaveImage(m, fn, util.IT_png); err != nil {
+		return err
+	}
+	return util.DoCrush(compress, fn)
+}
+
+// expandImageArgs replaces directories in args with the image files they contain.
+func expandImageArgs(args []string) (res []string) {
+	for _, arg := range args {
+		if b, err := util.IsDir(arg); err != nil || !b {
+			res = append(res, arg)
+			continue
+		}
+		filepath.Walk(arg, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				log.Println(p, err)
+				return nil
+			}
+			if _, ok := imageFormatMap[strings.ToLower(filepath.Ext(p))]; ok && !info.IsDir() {
+				res = append(res, p)
+			}
+			return nil
+		})
+	}
+	return res
+}
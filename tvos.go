@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"log"
+	"path"
+)
+
+type tvImageStackSpec struct {
+	Name   string
+	Width  int
+	Height int
+	Scales []int
+}
+
+type tvTopShelfSpec struct {
+	Name   string
+	Role   string
+	Width  int
+	Height int
+}
+
+var (
+	tvImageStackSpecifications = []tvImageStackSpec{
+		{"App Icon", 400, 240, []int{1, 2}},
+		{"App Icon - App Store", 1280, 768, []int{1}},
+	}
+	tvTopShelfSpecifications = []tvTopShelfSpec{
+		{"Top Shelf Image", "top-shelf-image", 1920, 720},
+		{"Top Shelf Image Wide", "top-shelf-image-wide", 2320, 720},
+	}
+)
+
+// GenerateTVAppIcon writes the "App Icon & Top Shelf Image" brand assets of tvOS from the layer images.
+func GenerateTVAppIcon() error {
+	names, layers, err := loadLayerImages()
+	if err != nil {
+		return err
+	}
+
+	dir := path.Join(outputPath, "appicon", "tvos", "Assets.xcassets", "App Icon & Top Shelf Image.brandassets")
+	contents := assetContents{Info: xcodeAssetInfo}
+	for _, spec := range tvImageStackSpecifications {
+		log.Println("generating", spec.Name+".imagestack")
+		err = writeImageStack(names, layers, path.Join(dir, spec.Name+".imagestack"), "imagestack", spec.Width, spec.Height, "tv", spec.Scales)
+		if err != nil {
+			return err
+		}
+		contents.Assets = append(contents.Assets, assetImage{
+			Filename: spec.Name + ".imagestack",
+			Idiom:    "tv",
+			Role:     "primary-app-icon",
+			Size:     fmt.Sprintf("%dx%d", spec.Width, spec.Height),
+		})
+	}
+
+	// the top shelf images are flat, the front layers are composed over the back layer
+	back := layers[len(layers)-1]
+	for _, spec := range tvTopShelfSpecifications {
+		fm := image.NewRGBA(image.Rect(0, 0, spec.Height*2, spec.Height*2))
+		for i := len(layers) - 2; i >= 0; i-- {
+			draw.Draw(fm, fm.Bounds(), renderLayer(layers[i], fm.Bounds().Dx(), fm.Bounds().Dy(), false), image.ZP, draw.Over)
+		}
+		m, err := composeBackgroundForeground(back, fm, &launchImageSpec{Width: spec.Width * 2, Height: spec.Height * 2})
+		if err != nil {
+			return err
+		}
+		log.Println("generating", spec.Name+".imageset")
+		if err = writeImageSetScales(m, dir, spec.Name, spec.Width, spec.Height, "tv", []int{1, 2}); err != nil {
+			return err
+		}
+		contents.Assets = append(contents.Assets, assetImage{
+			Filename: spec.Name + ".imageset",
+			Idiom:    "tv",
+			Role:     spec.Role,
+			Size:     fmt.Sprintf("%dx%d", spec.Width, spec.Height),
+		})
+	}
+	return writeContentsJson(dir, contents)
+}
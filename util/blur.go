@@ -0,0 +1,55 @@
+package util
+
+import (
+	"image"
+)
+
+// BlurAlpha approximates a gaussian blur of m with three passes of a box blur of the given radius.
+func BlurAlpha(m *image.Alpha, radius int) *image.Alpha {
+	rc := m.Bounds()
+	w, h := rc.Dx(), rc.Dy()
+	src := make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src[y*w+x] = int(m.AlphaAt(rc.Min.X+x, rc.Min.Y+y).A)
+		}
+	}
+	dst := make([]int, w*h)
+	for pass := 0; pass < 3; pass++ {
+		boxBlur(src, dst, w, h, radius, 1, w)
+		boxBlur(dst, src, h, w, radius, w, 1)
+	}
+
+	res := image.NewAlpha(rc)
+	for i, v := range src {
+		res.Pix[(i/w)*res.Stride+i%w] = uint8(v)
+	}
+	return res
+}
+
+// boxBlur blurs n lines of length l, step is the distance between neighbouring pixels of a line
+// and stride the distance between lines.
+func boxBlur(src, dst []int, l, n, radius, step, stride int) {
+	size := 2*radius + 1
+	for line := 0; line < n; line++ {
+		base := line * stride
+		sum := 0
+		for i := -radius; i <= radius; i++ {
+			sum += src[base+clampIndex(i, l)*step]
+		}
+		for i := 0; i < l; i++ {
+			dst[base+i*step] = sum / size
+			sum += src[base+clampIndex(i+radius+1, l)*step] - src[base+clampIndex(i-radius, l)*step]
+		}
+	}
+}
+
+func clampIndex(i, l int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= l {
+		return l - 1
+	}
+	return i
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"image"
 	"image/color"
+	"image/draw"
 )
 
 type Circle struct {
@@ -10,6 +11,10 @@ type Circle struct {
 	r int
 }
 
+func NewCircle(p image.Point, r int) *Circle {
+	return &Circle{p, r}
+}
+
 func (c *Circle) ColorModel() color.Model {
 	return color.AlphaModel
 }
@@ -26,3 +31,14 @@ func (c *Circle) At(x, y int) color.Color {
 	return color.Alpha{0}
 }
 
+// CircleCrop masks im with the largest circle centered in its bounds.
+func CircleCrop(im image.Image) *image.RGBA {
+	rc := im.Bounds()
+	r := rc.Dx()
+	if rc.Dy() < r {
+		r = rc.Dy()
+	}
+	m := image.NewRGBA(image.Rect(0, 0, rc.Dx(), rc.Dy()))
+	draw.DrawMask(m, m.Bounds(), im, rc.Min, NewCircle(image.Point{rc.Dx() / 2, rc.Dy() / 2}, r/2), image.ZP, draw.Over)
+	return m
+}
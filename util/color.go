@@ -0,0 +1,63 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+var (
+	err_invalid_color = errors.New("invalid color")
+)
+
+// ParseColor accepts #rgb, #rrggbb, #rrggbbaa, rgb(r,g,b) and rgba(r,g,b,a) notations.
+func ParseColor(s string) (color.NRGBA, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(s, "#"):
+		h := s[1:]
+		if len(h) == 3 {
+			h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+		}
+		if len(h) == 6 {
+			h += "ff"
+		}
+		if len(h) != 8 {
+			return color.NRGBA{}, err_invalid_color
+		}
+		v, err := strconv.ParseUint(h, 16, 32)
+		if err != nil {
+			return color.NRGBA{}, err_invalid_color
+		}
+		return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+	case strings.HasPrefix(s, "rgb(") || strings.HasPrefix(s, "rgba("):
+		if !strings.HasSuffix(s, ")") {
+			return color.NRGBA{}, err_invalid_color
+		}
+		fields := strings.Split(s[strings.Index(s, "(")+1:len(s)-1], ",")
+		if len(fields) != 3 && len(fields) != 4 {
+			return color.NRGBA{}, err_invalid_color
+		}
+		c := [4]uint8{0, 0, 0, 255}
+		for i, f := range fields {
+			v, err := strconv.ParseUint(strings.TrimSpace(f), 10, 8)
+			if err != nil {
+				return color.NRGBA{}, err_invalid_color
+			}
+			c[i] = uint8(v)
+		}
+		return color.NRGBA{c[0], c[1], c[2], c[3]}, nil
+	}
+	return color.NRGBA{}, err_invalid_color
+}
+
+// HexColor formats c as #rrggbb, or #aarrggbb when it is not fully opaque, which is the notation Android resources use.
+func HexColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0xff {
+		return fmt.Sprintf("#%02X%02X%02X", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02X%02X%02X%02X", n.A, n.R, n.G, n.B)
+}
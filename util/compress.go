@@ -1,6 +1,7 @@
 package util
 
 import (
+	"image"
 	"image/png"
 	"io/ioutil"
 	"os"
@@ -34,6 +35,17 @@ func Crush(filePath string) error {
 	return nil
 }
 
+// CompressPNG writes img to filePath at the best lossless compression, unlike Crush it keeps the color type of img.
+func CompressPNG(img image.Image, filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return encoder.Encode(file, img)
+}
+
 func crushFile(sourcefile, destfile string, speed int, compression png.CompressionLevel) error {
 	sourceFh, err := os.OpenFile(sourcefile, os.O_RDONLY, 0444)
 	if err != nil {
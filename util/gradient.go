@@ -0,0 +1,171 @@
+package util
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	FillSolid = iota
+	FillLinear
+	FillRadial
+)
+
+var (
+	err_invalid_fill = errors.New("invalid fill, expect a color, linear-gradient(...) or radial-gradient(...)")
+)
+
+// Fill is a solid color or a gradient that can be rendered at any size.
+type Fill struct {
+	Kind   int
+	Colors []color.NRGBA
+	// Angle of a linear gradient in degrees, CSS semantics: 0 points up, 90 to the right.
+	Angle float64
+}
+
+// ParseFill accepts a color in any notation ParseColor does, linear-gradient([<angle>deg,] c1, c2, ...)
+// or radial-gradient(c1, c2, ...), the color stops are evenly spaced.
+func ParseFill(s string) (*Fill, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var f Fill
+	switch {
+	case strings.HasPrefix(s, "linear-gradient(") && strings.HasSuffix(s, ")"):
+		f.Kind = FillLinear
+		f.Angle = 180
+	case strings.HasPrefix(s, "radial-gradient(") && strings.HasSuffix(s, ")"):
+		f.Kind = FillRadial
+	default:
+		c, err := ParseColor(s)
+		if err != nil {
+			return nil, err
+		}
+		return &Fill{Kind: FillSolid, Colors: []color.NRGBA{c}}, nil
+	}
+
+	args := splitFillArgs(s[strings.Index(s, "(")+1 : len(s)-1])
+	if f.Kind == FillLinear && len(args) > 0 && strings.HasSuffix(args[0], "deg") {
+		a, err := strconv.ParseFloat(strings.TrimSuffix(args[0], "deg"), 64)
+		if err != nil {
+			return nil, err_invalid_fill
+		}
+		f.Angle = a
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return nil, err_invalid_fill
+	}
+	for _, arg := range args {
+		c, err := ParseColor(arg)
+		if err != nil {
+			return nil, err
+		}
+		f.Colors = append(f.Colors, c)
+	}
+	return &f, nil
+}
+
+// splitFillArgs splits s on the commas that are not nested in parentheses, so rgb(...) stops survive.
+func splitFillArgs(s string) (args []string) {
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
+// bayerMatrix is the 4x4 ordered dithering threshold map, scaled to 0..1 by Render.
+var bayerMatrix = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// Render draws f onto a new w*h image, gradients are ordered dithered so that they do not band
+// when the channels are quantized to 8 bits.
+func (f *Fill) Render(w, h int) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	if f.Kind == FillSolid {
+		draw.Draw(m, m.Bounds(), &image.Uniform{f.Colors[0]}, image.ZP, draw.Src)
+		return m
+	}
+
+	cx, cy := float64(w)/2, float64(h)/2
+	sin, cos := math.Sincos(f.Angle * math.Pi / 180)
+	// the gradient line of CSS linear gradients spans the box corner to corner along the angle
+	length := math.Abs(float64(w)*sin) + math.Abs(float64(h)*cos)
+	radius := math.Hypot(cx, cy)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px, py := float64(x)+0.5-cx, float64(y)+0.5-cy
+			var t float64
+			if f.Kind == FillLinear {
+				t = (px*sin-py*cos)/length + 0.5
+			} else {
+				t = math.Hypot(px, py) / radius
+			}
+			threshold := (bayerMatrix[y%4][x%4] + 0.5) / 16
+			c := f.at(t)
+			quantize := func(v float64) uint8 {
+				return uint8(math.Min(255, math.Floor(v+threshold)))
+			}
+			m.Set(x, y, color.NRGBA{quantize(c[0]), quantize(c[1]), quantize(c[2]), quantize(c[3])})
+		}
+	}
+	return m
+}
+
+// at returns the unquantized channels of the color at position t.
+func (f *Fill) at(t float64) [4]float64 {
+	n := len(f.Colors)
+	if t < 0 {
+		t = 0
+	}
+	if t > 1 {
+		t = 1
+	}
+	i := 0
+	if n > 1 {
+		t *= float64(n - 1)
+		i = int(t)
+		if i == n-1 {
+			i--
+		}
+		t -= float64(i)
+	}
+	c1 := f.Colors[i]
+	c2 := c1
+	if n > 1 {
+		c2 = f.Colors[i+1]
+	}
+	lerp := func(a, b uint8) float64 {
+		return float64(a) + (float64(b)-float64(a))*t
+	}
+	return [4]float64{lerp(c1.R, c2.R), lerp(c1.G, c2.G), lerp(c1.B, c2.B), lerp(c1.A, c2.A)}
+}
+
+// FillImage is f rendered at a nominal size, consumers that recognize it render Fill again at their target size.
+type FillImage struct {
+	*image.RGBA
+	Fill *Fill
+}
+
+// Image renders f at w*h and keeps f so that it can be rendered again at other sizes.
+func (f *Fill) Image(w, h int) *FillImage {
+	return &FillImage{f.Render(w, h), f}
+}
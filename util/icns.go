@@ -0,0 +1,41 @@
+package util
+
+import (
+	"image"
+	"io"
+
+	"github.com/jackmordaunt/icns"
+	"github.com/nfnt/resize"
+)
+
+var (
+	// every resolution of a macOS iconset, each resampled from the master rather than taken from one size
+	icnsTypes = []icns.OsType{
+		{ID: "icp4", Size: 16},
+		{ID: "icp5", Size: 32},
+		{ID: "ic11", Size: 32},
+		{ID: "ic12", Size: 64},
+		{ID: "ic07", Size: 128},
+		{ID: "ic13", Size: 256},
+		{ID: "ic08", Size: 256},
+		{ID: "ic14", Size: 512},
+		{ID: "ic09", Size: 512},
+		{ID: "ic10", Size: 1024},
+	}
+)
+
+// EncodeICNS writes an icns file that embeds every resolution from 16x16 up to 512x512@2x.
+func EncodeICNS(w io.Writer, m image.Image) error {
+	set := &icns.IconSet{}
+	resized := make(map[uint]image.Image)
+	for _, t := range icnsTypes {
+		im, ok := resized[t.Size]
+		if !ok {
+			im = resize.Resize(t.Size, t.Size, m, resize.Lanczos3)
+			resized[t.Size] = im
+		}
+		set.Icons = append(set.Icons, &icns.Icon{Type: t, Image: im})
+	}
+	_, err := set.WriteTo(w)
+	return err
+}
@@ -0,0 +1,202 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/nfnt/resize"
+)
+
+const (
+	icoTypeIcon   = 1
+	icoTypeCursor = 2
+	// entries of this size are stored with a zero width/height byte and PNG compressed, smaller ones as DIB
+	icoMaxLength = 256
+)
+
+var (
+	// ICOSizes are the resolutions Windows looks for in an application icon.
+	ICOSizes = []int{16, 24, 32, 48, 64, 256}
+	// CURSizes are the cursor resolutions for 100%, 150% and 200% scaling.
+	CURSizes = []int{32, 48, 64}
+
+	err_invalid_ico_size = errors.New("ico sizes must be between 1 and 256")
+	err_invalid_hotspot  = errors.New("cursor hotspot must be inside the image")
+)
+
+type icoDirEntry struct {
+	Width      uint8
+	Height     uint8
+	ColorCount uint8
+	Reserved   uint8
+	// color planes for icons, X of the hotspot for cursors
+	Planes uint16
+	// bits per pixel for icons, Y of the hotspot for cursors
+	BitCount    uint16
+	BytesInRes  uint32
+	ImageOffset uint32
+}
+
+type bitmapInfoHeader struct {
+	Size          uint32
+	Width         int32
+	Height        int32
+	Planes        uint16
+	BitCount      uint16
+	Compression   uint32
+	SizeImage     uint32
+	XPelsPerMeter int32
+	YPelsPerMeter int32
+	ClrUsed       uint32
+	ClrImportant  uint32
+}
+
+// FitSizes returns the sizes that do not upscale m, or the smallest size when m is smaller than all of them.
+func FitSizes(m image.Image, sizes []int) (res []int) {
+	sz := m.Bounds().Size()
+	length := sz.X
+	if sz.Y > length {
+		length = sz.Y
+	}
+	for _, size := range sizes {
+		if size <= length {
+			res = append(res, size)
+		}
+	}
+	if len(res) == 0 && len(sizes) > 0 {
+		res = append(res, sizes[0])
+	}
+	return res
+}
+
+// EncodeICO writes an ico file that embeds m resampled to every size in sizes,
+// the 256 entry is stored as PNG and the smaller ones as 32-bit DIB.
+func EncodeICO(w io.Writer, m image.Image, sizes []int) error {
+	return encodeIconDir(w, icoTypeIcon, m, sizes, image.Point{})
+}
+
+// EncodeCUR writes a cur file like EncodeICO, hotspot is in pixels of m and scaled to every size.
+func EncodeCUR(w io.Writer, m image.Image, sizes []int, hotspot image.Point) error {
+	return encodeIconDir(w, icoTypeCursor, m, sizes, hotspot)
+}
+
+// SaveCursor writes img as a multi-resolution cursor to savePath.
+func SaveCursor(img image.Image, savePath string, hotspot image.Point) error {
+	file, err := os.OpenFile(savePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return EncodeCUR(file, img, FitSizes(img, CURSizes), hotspot)
+}
+
+func encodeIconDir(w io.Writer, typ uint16, m image.Image, sizes []int, hotspot image.Point) error {
+	sz := m.Bounds().Size()
+	if typ == icoTypeCursor && !hotspot.In(image.Rect(0, 0, sz.X, sz.Y)) {
+		return err_invalid_hotspot
+	}
+	length := sz.X
+	if sz.Y > length {
+		length = sz.Y
+	}
+	if sz.X != sz.Y {
+		// keep the aspect ratio, the hotspot moves with the centered image
+		hotspot = hotspot.Add(image.Point{(length - sz.X) / 2, (length - sz.Y) / 2})
+		m = Center(m, length, length)
+	}
+
+	var images [][]byte
+	for _, size := range sizes {
+		if size <= 0 || size > icoMaxLength {
+			return err_invalid_ico_size
+		}
+		im := m
+		if size != length {
+			im = resize.Resize(uint(size), uint(size), m, resize.Lanczos3)
+		}
+		var buf bytes.Buffer
+		var err error
+		if size == icoMaxLength {
+			err = png.Encode(&buf, im)
+		} else {
+			err = encodeDIB(&buf, im)
+		}
+		if err != nil {
+			return err
+		}
+		images = append(images, buf.Bytes())
+	}
+
+	header := []uint16{0, typ, uint16(len(sizes))}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	offset := uint32(6 + 16*len(sizes))
+	for i, size := range sizes {
+		entry := icoDirEntry{
+			Width:       uint8(size % icoMaxLength),
+			Height:      uint8(size % icoMaxLength),
+			Planes:      1,
+			BitCount:    32,
+			BytesInRes:  uint32(len(images[i])),
+			ImageOffset: offset,
+		}
+		if typ == icoTypeCursor {
+			entry.Planes = uint16(hotspot.X * size / length)
+			entry.BitCount = uint16(hotspot.Y * size / length)
+		}
+		if err := binary.Write(w, binary.LittleEndian, entry); err != nil {
+			return err
+		}
+		offset += entry.BytesInRes
+	}
+	for _, data := range images {
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeDIB writes m as a bottom-up 32-bit BGRA bitmap followed by the 1-bit AND mask, the layout of ico entries.
+func encodeDIB(w io.Writer, m image.Image) error {
+	rc := m.Bounds()
+	width, height := rc.Dx(), rc.Dy()
+	// mask rows are padded to 32 bits
+	maskStride := (width + 31) / 32 * 4
+	header := bitmapInfoHeader{
+		Size:      40,
+		Width:     int32(width),
+		Height:    int32(height * 2),
+		Planes:    1,
+		BitCount:  32,
+		SizeImage: uint32(width*height*4 + maskStride*height),
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	pixels := make([]byte, 0, width*height*4)
+	mask := make([]byte, maskStride*height)
+	for y := height - 1; y >= 0; y-- {
+		row := height - 1 - y
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(m.At(rc.Min.X+x, rc.Min.Y+y)).(color.NRGBA)
+			pixels = append(pixels, c.B, c.G, c.R, c.A)
+			if c.A == 0 {
+				mask[row*maskStride+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	if _, err := w.Write(pixels); err != nil {
+		return err
+	}
+	_, err := w.Write(mask)
+	return err
+}
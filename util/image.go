@@ -13,7 +13,7 @@ import (
 	"log"
 	"os"
 
-	"github.com/biessek/golang-ico"
+	_ "github.com/biessek/golang-ico"
 	"github.com/chai2010/tiff"
 	"github.com/chai2010/webp"
 	"github.com/jackmordaunt/icns"
@@ -29,6 +29,7 @@ const (
 	IT_icns
 	IT_ico
 	IT_bmp
+	IT_cur
 )
 
 var (
@@ -60,7 +61,9 @@ func SaveImage(img image.Image, savePath string, imageType int) (err error) {
 	case IT_icns:
 		err = icns.Encode(file, img)
 	case IT_ico:
-		err = ico.Encode(file, img)
+		err = EncodeICO(file, img, FitSizes(img, ICOSizes))
+	case IT_cur:
+		err = EncodeCUR(file, img, FitSizes(img, CURSizes), image.Point{})
 	case IT_bmp:
 		err = gobmp.Encode(file, img)
 	default:
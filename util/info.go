@@ -2,6 +2,7 @@ package util
 
 import (
 	"errors"
+	"image"
 	"image/color"
 	"image/draw"
 	"log"
@@ -33,3 +34,38 @@ func Info(uri string) (map[color.Color]int, error) {
 	}
 	return cm, nil
 }
+
+// DominantColor returns the average color of the most common color bucket among the visible pixels of im.
+func DominantColor(im image.Image) color.NRGBA {
+	type sum struct {
+		r, g, b, n uint64
+	}
+	buckets := make(map[uint32]*sum)
+	var best *sum
+	rc := im.Bounds()
+	for x := rc.Min.X; x < rc.Max.X; x++ {
+		for y := rc.Min.Y; y < rc.Max.Y; y++ {
+			c := color.NRGBAModel.Convert(im.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				continue
+			}
+			key := uint32(c.R>>4)<<8 | uint32(c.G>>4)<<4 | uint32(c.B>>4)
+			s, ok := buckets[key]
+			if !ok {
+				s = &sum{}
+				buckets[key] = s
+			}
+			s.r += uint64(c.R)
+			s.g += uint64(c.G)
+			s.b += uint64(c.B)
+			s.n++
+			if best == nil || s.n > best.n {
+				best = s
+			}
+		}
+	}
+	if best == nil {
+		return color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	}
+	return color.NRGBA{uint8(best.r / best.n), uint8(best.g / best.n), uint8(best.b / best.n), 0xff}
+}
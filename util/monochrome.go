@@ -0,0 +1,41 @@
+package util
+
+import (
+	"image"
+	"image/color"
+)
+
+// Monochrome desaturates im into a single-color alpha mask. With threshold 0 the source alpha is kept as is,
+// otherwise only pixels whose luminance is below threshold remain opaque.
+func Monochrome(im image.Image, threshold uint8) image.Image {
+	rc := im.Bounds()
+	img := &notOpaqueRGBA{image.NewRGBA(image.Rect(0, 0, rc.Dx(), rc.Dy()))}
+	for x := 0; x < rc.Dx(); x++ {
+		for y := 0; y < rc.Dy(); y++ {
+			c := color.NRGBAModel.Convert(im.At(rc.Min.X+x, rc.Min.Y+y)).(color.NRGBA)
+			a := c.A
+			if threshold > 0 {
+				luma := (299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000
+				if luma >= uint32(threshold) {
+					a = 0
+				}
+			}
+			img.Set(x, y, color.NRGBA{255, 255, 255, a})
+		}
+	}
+	return img
+}
+
+// Grayscale converts im to its luminance while keeping the alpha channel.
+func Grayscale(im image.Image) image.Image {
+	rc := im.Bounds()
+	img := &notOpaqueRGBA{image.NewRGBA(image.Rect(0, 0, rc.Dx(), rc.Dy()))}
+	for x := 0; x < rc.Dx(); x++ {
+		for y := 0; y < rc.Dy(); y++ {
+			c := color.NRGBAModel.Convert(im.At(rc.Min.X+x, rc.Min.Y+y)).(color.NRGBA)
+			luma := uint8((299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000)
+			img.Set(x, y, color.NRGBA{luma, luma, luma, c.A})
+		}
+	}
+	return img
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/nfnt/resize"
+)
+
+var (
+	err_invalid_nine_patch = errors.New("invalid nine-patch image")
+)
+
+// ScaleNinePatch scales the content of a nine-patch image by ratio and rebuilds its 1px marker border,
+// so that the stretch and padding markers stay solid and aligned with the scaled content.
+func ScaleNinePatch(im image.Image, ratio float64) (image.Image, error) {
+	rc := im.Bounds()
+	w, h := rc.Dx()-2, rc.Dy()-2
+	if w <= 0 || h <= 0 {
+		return nil, err_invalid_nine_patch
+	}
+	nw, nh := int(float64(w)*ratio+0.5), int(float64(h)*ratio+0.5)
+	if nw <= 0 || nh <= 0 {
+		return nil, err_invalid_nine_patch
+	}
+
+	content := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(content, content.Bounds(), im, rc.Min.Add(image.Point{1, 1}), draw.Src)
+
+	m := &notOpaqueRGBA{image.NewRGBA(image.Rect(0, 0, nw+2, nh+2))}
+	draw.Draw(m, image.Rect(1, 1, nw+1, nh+1), resize.Resize(uint(nw), uint(nh), content, resize.Bilinear), image.ZP, draw.Src)
+
+	top := scaleMarkers(im, rc.Min.X+1, rc.Min.Y, 1, 0, w, nw)
+	bottom := scaleMarkers(im, rc.Min.X+1, rc.Max.Y-1, 1, 0, w, nw)
+	left := scaleMarkers(im, rc.Min.X, rc.Min.Y+1, 0, 1, h, nh)
+	right := scaleMarkers(im, rc.Max.X-1, rc.Min.Y+1, 0, 1, h, nh)
+	for i := 0; i < nw; i++ {
+		m.Set(i+1, 0, top[i])
+		m.Set(i+1, nh+1, bottom[i])
+	}
+	for i := 0; i < nh; i++ {
+		m.Set(0, i+1, left[i])
+		m.Set(nw+1, i+1, right[i])
+	}
+	return m, nil
+}
+
+// scaleMarkers resamples a marker line of length n starting at (x, y) to length nn with nearest neighbour,
+// snapping every pixel to either fully opaque or fully transparent.
+func scaleMarkers(im image.Image, x, y, dx, dy, n, nn int) []color.Color {
+	marker := func(i int) color.Color {
+		c := color.NRGBAModel.Convert(im.At(x+i*dx, y+i*dy)).(color.NRGBA)
+		if c.A < 0x80 {
+			return color.Transparent
+		}
+		c.A = 0xff
+		return c
+	}
+
+	res := make([]color.Color, nn)
+	for i := range res {
+		res[i] = marker(int((float64(i) + 0.5) * float64(n) / float64(nn)))
+	}
+	// a marker run shorter than the scale step must not vanish
+	for i := 0; i < n; i++ {
+		if c := marker(i); c != color.Transparent {
+			j := int(float64(i) * float64(nn) / float64(n))
+			if j < nn && res[j] == color.Transparent {
+				res[j] = c
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	PNGColorGray      = 0
+	PNGColorRGB       = 2
+	PNGColorPalette   = 3
+	PNGColorGrayAlpha = 4
+	PNGColorRGBA      = 6
+
+	// only the leading bytes of IHDR and iCCP are read: the 13-byte header and the profile name,
+	// a keyword of at most 79 bytes plus its null separator
+	pngChunkReadLength = 80
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	err_not_png = errors.New("not a PNG file")
+)
+
+type PNGHeader struct {
+	Width     int
+	Height    int
+	BitDepth  uint8
+	ColorType uint8
+}
+
+// ReadPNGHeader reads the IHDR chunk of the PNG file at fn.
+func ReadPNGHeader(fn string) (*PNGHeader, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf [33]byte
+	if _, err = io.ReadFull(f, buf[:]); err != nil {
+		return nil, err_not_png
+	}
+	if !bytes.Equal(buf[:8], pngSignature) || string(buf[12:16]) != "IHDR" {
+		return nil, err_not_png
+	}
+	return &PNGHeader{
+		Width:     int(binary.BigEndian.Uint32(buf[16:20])),
+		Height:    int(binary.BigEndian.Uint32(buf[20:24])),
+		BitDepth:  buf[24],
+		ColorType: buf[25],
+	}, nil
+}
+
+type PNGInfo struct {
+	PNGHeader
+	// Chunks lists the chunk types in file order.
+	Chunks []string
+	// ICCProfile is the profile name of the iCCP chunk, if any.
+	ICCProfile string
+}
+
+// ReadPNGInfo reads the header and the chunk layout of the PNG stream r without decoding pixels.
+func ReadPNGInfo(r io.Reader) (*PNGInfo, error) {
+	var sig [8]byte
+	if _, err := io.ReadFull(r, sig[:]); err != nil || !bytes.Equal(sig[:], pngSignature) {
+		return nil, err_not_png
+	}
+	info := &PNGInfo{}
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(hdr[:4])
+		typ := string(hdr[4:8])
+		info.Chunks = append(info.Chunks, typ)
+		switch typ {
+		case "IHDR", "iCCP":
+			// the chunk length comes from the file, it must not decide how much is allocated
+			n := length
+			if n > pngChunkReadLength {
+				n = pngChunkReadLength
+			}
+			data := make([]byte, n)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			if _, err := io.CopyN(ioutil.Discard, r, int64(length-n)); err != nil {
+				return nil, err
+			}
+			if typ == "IHDR" && length >= 10 {
+				info.Width = int(binary.BigEndian.Uint32(data[0:4]))
+				info.Height = int(binary.BigEndian.Uint32(data[4:8]))
+				info.BitDepth = data[8]
+				info.ColorType = data[9]
+			}
+			if typ == "iCCP" {
+				if i := bytes.IndexByte(data, 0); i >= 0 {
+					info.ICCProfile = string(data[:i])
+				}
+			}
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r, int64(length)); err != nil {
+				return nil, err
+			}
+		}
+		// skip the CRC
+		if _, err := io.CopyN(ioutil.Discard, r, 4); err != nil {
+			return nil, err
+		}
+		if typ == "IEND" {
+			return info, nil
+		}
+	}
+}
+
+// HasChunk reports whether the PNG stream contains a chunk of type typ.
+func (info *PNGInfo) HasChunk(typ string) bool {
+	for _, c := range info.Chunks {
+		if c == typ {
+			return true
+		}
+	}
+	return false
+}
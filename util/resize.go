@@ -2,6 +2,7 @@ package util
 
 import (
 	"image"
+	"image/draw"
 	"log"
 
 	"github.com/nfnt/resize"
@@ -21,3 +22,35 @@ func Resize(uri string, w, h uint) (image.Image, error) {
 
 	return resize.Resize(w, h, im, resize.Bilinear), nil
 }
+
+// Fit scales im to fit inside a w*h box while keeping its aspect ratio.
+func Fit(im image.Image, w, h int) image.Image {
+	sz := im.Bounds().Size()
+	if sz.X*h > sz.Y*w {
+		return resize.Resize(uint(w), 0, im, resize.Bilinear)
+	}
+	return resize.Resize(0, uint(h), im, resize.Bilinear)
+}
+
+// Cover scales im to cover a w*h box while keeping its aspect ratio, then crops the centered w*h area.
+func Cover(im image.Image, w, h int) image.Image {
+	sz := im.Bounds().Size()
+	if sz.X*h > sz.Y*w {
+		im = resize.Resize(0, uint(h), im, resize.Bilinear)
+	} else {
+		im = resize.Resize(uint(w), 0, im, resize.Bilinear)
+	}
+	sz = im.Bounds().Size()
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(m, m.Bounds(), im, im.Bounds().Min.Add(image.Point{(sz.X - w) / 2, (sz.Y - h) / 2}), draw.Src)
+	return m
+}
+
+// Center draws im in the middle of a transparent w*h canvas.
+func Center(im image.Image, w, h int) *image.RGBA {
+	sz := im.Bounds().Size()
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	x, y := (w-sz.X)/2, (h-sz.Y)/2
+	draw.Draw(m, image.Rect(x, y, x+sz.X, y+sz.Y), im, im.Bounds().Min, draw.Over)
+	return m
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// RoundedRect is an anti-aliased alpha mask of a rectangle with rounded corners.
+type RoundedRect struct {
+	rect image.Rectangle
+	r    float64
+}
+
+func NewRoundedRect(rect image.Rectangle, r float64) *RoundedRect {
+	return &RoundedRect{rect, r}
+}
+
+func (rr *RoundedRect) ColorModel() color.Model {
+	return color.AlphaModel
+}
+
+func (rr *RoundedRect) Bounds() image.Rectangle {
+	return rr.rect
+}
+
+func (rr *RoundedRect) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(rr.rect)) {
+		return color.Alpha{0}
+	}
+	if rr.r < 1 {
+		return color.Alpha{255}
+	}
+	px, py := float64(x)+0.5, float64(y)+0.5
+	cx := math.Max(float64(rr.rect.Min.X)+rr.r, math.Min(px, float64(rr.rect.Max.X)-rr.r))
+	cy := math.Max(float64(rr.rect.Min.Y)+rr.r, math.Min(py, float64(rr.rect.Max.Y)-rr.r))
+	// signed distance to the rounded edge, converted to pixel coverage
+	coverage := 0.5 - (math.Hypot(px-cx, py-cy) - rr.r)
+	if coverage >= 1 {
+		return color.Alpha{255}
+	}
+	if coverage <= 0 {
+		return color.Alpha{0}
+	}
+	return color.Alpha{uint8(coverage * 255)}
+}
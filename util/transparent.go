@@ -16,6 +16,14 @@ func (i *notOpaqueRGBA) Opaque() bool {
 	return false
 }
 
+// ForceAlpha copies im into an image that is always encoded with an alpha channel, even if it is fully opaque.
+func ForceAlpha(im image.Image) image.Image {
+	rc := im.Bounds()
+	img := &notOpaqueRGBA{image.NewRGBA(image.Rect(0, 0, rc.Dx(), rc.Dy()))}
+	draw.Draw(img, img.Bounds(), im, rc.Min, draw.Src)
+	return img
+}
+
 func Transparent(uri string, red, green, blue uint32, transparentWhiteDirect bool) (image.Image, error) {
 	r, err := OpenURI(uri)
 	if err != nil {
@@ -28,10 +36,15 @@ func Transparent(uri string, red, green, blue uint32, transparentWhiteDirect boo
 	}
 	log.Println("found format:", format)
 
+	return TransparentImage(im, red, green, blue, transparentWhiteDirect), nil
+}
+
+// TransparentImage makes the pixels brighter (or darker when transparentWhiteDirect is set) than the thresholds transparent.
+func TransparentImage(im image.Image, red, green, blue uint32, transparentWhiteDirect bool) image.Image {
 	rc := im.Bounds()
 	// https://groob.io/posts/image-draw-intro/
-	img := &notOpaqueRGBA{image.NewRGBA(im.Bounds())}
-	draw.Draw(img, rc, im, image.ZP, draw.Src)
+	img := &notOpaqueRGBA{image.NewRGBA(image.Rect(0, 0, rc.Dx(), rc.Dy()))}
+	draw.Draw(img, img.Bounds(), im, rc.Min, draw.Src)
 
 	for x := 0; x < rc.Dx(); x++ {
 		for y := 0; y < rc.Dy(); y++ {
@@ -48,5 +61,107 @@ func Transparent(uri string, red, green, blue uint32, transparentWhiteDirect boo
 			}
 		}
 	}
-	return img, nil
+	return img
+}
+
+// TransparentRatio returns the fraction of pixels in im that are more than half transparent.
+func TransparentRatio(im image.Image) float64 {
+	rc := im.Bounds()
+	if rc.Empty() {
+		return 0
+	}
+	count := 0
+	for x := rc.Min.X; x < rc.Max.X; x++ {
+		for y := rc.Min.Y; y < rc.Max.Y; y++ {
+			if _, _, _, a := im.At(x, y).RGBA(); a < 0x8000 {
+				count++
+			}
+		}
+	}
+	return float64(count) / float64(rc.Dx()*rc.Dy())
+}
+
+// Flatten draws im over an opaque bg so that the result has no transparency.
+func Flatten(im image.Image, bg color.Color) image.Image {
+	rc := im.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, rc.Dx(), rc.Dy()))
+	draw.Draw(img, img.Bounds(), &image.Uniform{bg}, image.ZP, draw.Src)
+	draw.Draw(img, img.Bounds(), im, rc.Min, draw.Over)
+	return img
+}
+
+// HasTransparency reports whether any pixel of im is not fully opaque.
+func HasTransparency(im image.Image) bool {
+	if o, ok := im.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return false
+	}
+	rc := im.Bounds()
+	for y := rc.Min.Y; y < rc.Max.Y; y++ {
+		for x := rc.Min.X; x < rc.Max.X; x++ {
+			if _, _, _, a := im.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RemoveMatte makes the solid background around the artwork of im transparent: the region connected to the edges
+// whose color is within tolerance of the corner color. The artwork is kept as is, also where it has the matte color,
+// and im is returned unchanged when the four corners do not agree on a matte color.
+func RemoveMatte(im image.Image, tolerance uint8) image.Image {
+	rc := im.Bounds()
+	img := &notOpaqueRGBA{image.NewRGBA(image.Rect(0, 0, rc.Dx(), rc.Dy()))}
+	draw.Draw(img, img.Bounds(), im, rc.Min, draw.Src)
+	w, h := rc.Dx(), rc.Dy()
+	if w == 0 || h == 0 {
+		return img
+	}
+
+	matte := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA)
+	near := func(x, y int) bool {
+		c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+		return absDiff(c.R, matte.R) <= tolerance && absDiff(c.G, matte.G) <= tolerance &&
+			absDiff(c.B, matte.B) <= tolerance && absDiff(c.A, matte.A) <= tolerance
+	}
+	if matte.A == 0 || !near(w-1, 0) || !near(0, h-1) || !near(w-1, h-1) {
+		return img
+	}
+
+	visited := make([]bool, w*h)
+	var stack []image.Point
+	push := func(x, y int) {
+		if x < 0 || y < 0 || x >= w || y >= h || visited[y*w+x] {
+			return
+		}
+		visited[y*w+x] = true
+		if near(x, y) {
+			stack = append(stack, image.Point{x, y})
+		}
+	}
+	for x := 0; x < w; x++ {
+		push(x, 0)
+		push(x, h-1)
+	}
+	for y := 0; y < h; y++ {
+		push(0, y)
+		push(w-1, y)
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		img.Set(p.X, p.Y, color.Transparent)
+		push(p.X-1, p.Y)
+		push(p.X+1, p.Y)
+		push(p.X, p.Y-1)
+		push(p.X, p.Y+1)
+	}
+	return img
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
 }
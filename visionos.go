@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"path"
+)
+
+const (
+	visionAppIconLength = 1024
+)
+
+// GenerateVisionAppIcon writes the three-layer AppIcon.solidimagestack of visionOS from the layer images.
+func GenerateVisionAppIcon() error {
+	// unlike tvOS, visionOS always takes the front, middle and back layers
+	if len(layerImagePaths) != 3 {
+		return errors.New("visionOS app icons require 3 layer images, ordered from front to back")
+	}
+	names, layers, err := loadLayerImages()
+	if err != nil {
+		return err
+	}
+
+	dir := path.Join(outputPath, "appicon", "visionos", "Assets.xcassets", "AppIcon.solidimagestack")
+	log.Println("generating", dir)
+	return writeImageStack(names, layers, dir, "solidimagestack", visionAppIconLength/2, visionAppIconLength/2, "vision", []int{2})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"os"
+	"path"
+
+	"github.com/missdeer/yairc/util"
+)
+
+var (
+	watchAppIconSpecifications = []appIconSpec{
+		{24, "watch", 2, "notificationCenter", "38mm", "AppIcon24@2x.png"},
+		{27.5, "watch", 2, "notificationCenter", "42mm", "AppIcon27.5@2x.png"},
+		{33, "watch", 2, "notificationCenter", "45mm", "AppIcon33@2x.png"},
+		{29, "watch", 2, "companionSettings", "", "AppIcon29@2x.png"},
+		{29, "watch", 3, "companionSettings", "", "AppIcon29@3x.png"},
+		{40, "watch", 2, "appLauncher", "38mm", "AppIcon40@2x.png"},
+		{44, "watch", 2, "appLauncher", "40mm", "AppIcon44@2x.png"},
+		{46, "watch", 2, "appLauncher", "41mm", "AppIcon46@2x.png"},
+		{50, "watch", 2, "appLauncher", "44mm", "AppIcon50@2x.png"},
+		{51, "watch", 2, "appLauncher", "45mm", "AppIcon51@2x.png"},
+		{54, "watch", 2, "appLauncher", "49mm", "AppIcon54@2x.png"},
+		{86, "watch", 2, "quickLook", "38mm", "AppIcon86@2x.png"},
+		{98, "watch", 2, "quickLook", "42mm", "AppIcon98@2x.png"},
+		{108, "watch", 2, "quickLook", "44mm", "AppIcon108@2x.png"},
+		{117, "watch", 2, "quickLook", "45mm", "AppIcon117@2x.png"},
+		{129, "watch", 2, "quickLook", "49mm", "AppIcon129@2x.png"},
+		{1024, "watch-marketing", 1, "", "", "AppIcon1024.png"},
+	}
+)
+
+// GenerateWatchAppIcon writes the watchOS AppIcon.appiconset. watchOS applies the circular mask itself,
+// the icons only need to be opaque.
+func GenerateWatchAppIcon(origin string) error {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	dir := path.Join(outputPath, "appicon", "watchos", "Assets.xcassets", "AppIcon.appiconset")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeAppIconSet(util.Flatten(m, color.Black), dir, watchAppIconSpecifications, nil)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/missdeer/yairc/util"
+	"github.com/nfnt/resize"
+)
+
+const (
+	// the maskable safe zone is a centered circle with 80% of the icon edge as diameter
+	maskableSafeZone = 0.8
+	// part of the short edge of a tile covered by the logo, the tile color fills the rest
+	msTileLogoRatio = 0.5
+)
+
+var (
+	faviconIcoSizes = []int{16, 32, 48}
+	webPNGIcons     = []struct {
+		Filename string
+		Length   int
+		Manifest bool
+	}{
+		{"favicon-16x16.png", 16, false},
+		{"favicon-32x32.png", 32, false},
+		{"android-chrome-192x192.png", 192, true},
+		{"android-chrome-512x512.png", 512, true},
+	}
+	maskableIcons = []struct {
+		Filename string
+		Length   int
+	}{
+		{"android-chrome-maskable-192x192.png", 192},
+		{"android-chrome-maskable-512x512.png", 512},
+	}
+	// Windows renders the tiles at these sizes, not at the sizes in their names
+	msTileSpecifications = []struct {
+		Filename string
+		Width    int
+		Height   int
+	}{
+		{"mstile-70x70.png", 128, 128},
+		{"mstile-144x144.png", 144, 144},
+		{"mstile-150x150.png", 270, 270},
+		{"mstile-310x150.png", 558, 270},
+		{"mstile-310x310.png", 558, 558},
+	}
+
+	browserConfigXml = template.Must(template.New("browserconfig.xml").Parse(`<?xml version="1.0" encoding="utf-8"?>
+<browserconfig>
+    <msapplication>
+        <tile>
+            <square70x70logo src="/mstile-70x70.png"/>
+            <square150x150logo src="/mstile-150x150.png"/>
+            <wide310x150logo src="/mstile-310x150.png"/>
+            <square310x310logo src="/mstile-310x310.png"/>
+            <TileColor>{{.}}</TileColor>
+        </tile>
+    </msapplication>
+</browserconfig>
+`))
+	webHeadSnippet = template.Must(template.New("head").Parse(`<link rel="icon" href="/favicon.ico" sizes="16x16 32x32 48x48">
+<link rel="icon" type="image/png" sizes="32x32" href="/favicon-32x32.png">
+<link rel="icon" type="image/png" sizes="16x16" href="/favicon-16x16.png">
+<link rel="apple-touch-icon" sizes="180x180" href="/apple-touch-icon.png">
+<link rel="manifest" href="/site.webmanifest">
+<meta name="msapplication-config" content="/browserconfig.xml">
+<meta name="msapplication-TileColor" content="{{.}}">
+<meta name="theme-color" content="{{.}}">
+`))
+)
+
+type webManifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+type webManifest struct {
+	Name            string            `json:"name"`
+	ShortName       string            `json:"short_name"`
+	Icons           []webManifestIcon `json:"icons"`
+	ThemeColor      string            `json:"theme_color"`
+	BackgroundColor string            `json:"background_color"`
+	Display         string            `json:"display"`
+}
+
+// GenerateWebIcons writes the favicons, the PWA icons, the Windows tiles and their manifests into appicon/web,
+// then prints the HTML to put in <head>.
+func GenerateWebIcons(origin string) error {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	m = squareAppIcon(m)
+
+	bc := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	if backgroundColor != "" {
+		if bc, err = util.ParseColor(backgroundColor); err != nil {
+			log.Println(backgroundColor, err)
+			return err
+		}
+		bc.A = 0xff
+	}
+	hex := fmt.Sprintf("#%02x%02x%02x", bc.R, bc.G, bc.B)
+
+	dir := path.Join(outputPath, "appicon", "web")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	fn := path.Join(dir, "favicon.ico")
+	log.Println("generating", fn)
+	fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = util.EncodeICO(fd, m, faviconIcoSizes)
+	fd.Close()
+	if err != nil {
+		log.Println(fn, err)
+		return err
+	}
+
+	for _, icon := range webPNGIcons {
+		fn := path.Join(dir, icon.Filename)
+		if err = saveAndCrush(resize.Resize(uint(icon.Length), uint(icon.Length), m, resize.Bilinear), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+	}
+
+	// iOS fills the transparent area of touch icons with black
+	fn = path.Join(dir, "apple-touch-icon.png")
+	if err = saveAndCrush(resize.Resize(180, 180, util.Flatten(m, bc), resize.Bilinear), fn); err != nil {
+		log.Println(fn, err)
+		return err
+	}
+
+	for _, icon := range maskableIcons {
+		length := int(float64(icon.Length) * maskableSafeZone)
+		canvas := image.NewRGBA(image.Rect(0, 0, icon.Length, icon.Length))
+		draw.Draw(canvas, canvas.Bounds(), &image.Uniform{bc}, image.ZP, draw.Src)
+		fg := util.Center(resize.Resize(uint(length), uint(length), m, resize.Bilinear), icon.Length, icon.Length)
+		draw.Draw(canvas, canvas.Bounds(), fg, image.ZP, draw.Over)
+		fn := path.Join(dir, icon.Filename)
+		if err = saveAndCrush(canvas, fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+	}
+
+	for _, tile := range msTileSpecifications {
+		short := tile.Width
+		if tile.Height < short {
+			short = tile.Height
+		}
+		length := int(float64(short) * msTileLogoRatio)
+		logo := resize.Resize(uint(length), uint(length), m, resize.Bilinear)
+		fn := path.Join(dir, tile.Filename)
+		if err = saveAndCrush(util.Center(logo, tile.Width, tile.Height), fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+	}
+
+	fd, err = os.OpenFile(path.Join(dir, "browserconfig.xml"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = browserConfigXml.Execute(fd, hex)
+	fd.Close()
+	if err != nil {
+		return err
+	}
+
+	name := appName
+	if name == "" {
+		// browsers reject a manifest without a name when offering to install the app
+		name = "App"
+	}
+	manifest := webManifest{
+		Name:            name,
+		ShortName:       name,
+		ThemeColor:      hex,
+		BackgroundColor: hex,
+		Display:         "standalone",
+	}
+	for _, icon := range webPNGIcons {
+		if !icon.Manifest {
+			continue
+		}
+		manifest.Icons = append(manifest.Icons, webManifestIcon{
+			Src:   "/" + icon.Filename,
+			Sizes: fmt.Sprintf("%dx%d", icon.Length, icon.Length),
+			Type:  "image/png",
+		})
+	}
+	for _, icon := range maskableIcons {
+		manifest.Icons = append(manifest.Icons, webManifestIcon{
+			Src:     "/" + icon.Filename,
+			Sizes:   fmt.Sprintf("%dx%d", icon.Length, icon.Length),
+			Type:    "image/png",
+			Purpose: "maskable",
+		})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(path.Join(dir, "site.webmanifest"), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return webHeadSnippet.Execute(os.Stdout, hex)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+
+	"github.com/missdeer/yairc/util"
+	"github.com/nfnt/resize"
+)
+
+const (
+	// part of the edge the logo covers in plated target sizes, the shell draws them on a plate that needs the margin,
+	// unplated ones are full-bleed
+	msixPlatedLogoRatio = 0.75
+)
+
+// msixScales are the scale qualifiers of MSIX visual assets, in percent.
+var msixScales = []int{100, 125, 150, 200, 400}
+
+// msixAssetSpec is an MSIX visual asset with its size at scale-100, Ratio is the part of the short edge the logo covers.
+type msixAssetSpec struct {
+	Name    string
+	Width   int
+	Height  int
+	Ratio   float64
+	Compose bool
+}
+
+var (
+	msixAssetSpecifications = []msixAssetSpec{
+		{"Square44x44Logo", 44, 44, 1, false},
+		{"SmallTile", 71, 71, 0.66, false},
+		{"Square150x150Logo", 150, 150, 0.66, false},
+		{"Wide310x150Logo", 310, 150, 0, true},
+		{"LargeTile", 310, 310, 0.66, false},
+		{"StoreLogo", 50, 50, 1, false},
+		{"SplashScreen", 620, 300, 0, true},
+	}
+	// the taskbar, Start and Explorer pick Square44x44Logo by pixel size rather than by scale
+	msixTargetSizes = []int{16, 20, 24, 30, 32, 36, 40, 48, 60, 64, 72, 80, 96, 256}
+)
+
+// GenerateWindowsAppIcon writes app.ico with every resolution Windows uses, from 16x16 up to the PNG compressed 256x256,
+// and the MSIX visual assets into Images.
+func GenerateWindowsAppIcon(origin string) error {
+	reader, err := util.OpenURI(origin)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+	defer reader.Close()
+	m, _, err := util.ImageDecode(reader)
+	if err != nil {
+		log.Println(origin, err)
+		return err
+	}
+
+	dir := path.Join(outputPath, "appicon", "windows")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fn := path.Join(dir, "app.ico")
+	log.Println("generating", fn)
+	fd, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = util.EncodeICO(fd, squareAppIcon(m), util.ICOSizes)
+	fd.Close()
+	if err != nil {
+		log.Println(fn, err)
+		return err
+	}
+	return generateMSIXAssets(m, path.Join(dir, "Images"))
+}
+
+// generateMSIXAssets writes every tile and logo at every scale, plus the plated and unplated target sizes of Square44x44Logo.
+// Wide tiles and the splash screen are composed from the background and the foreground like launch images.
+func generateMSIXAssets(m image.Image, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	m = squareAppIcon(m)
+
+	bm, fm, err := loadMSIXBackgroundForeground(m)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range msixAssetSpecifications {
+		for _, scale := range msixScales {
+			w, h := (spec.Width*scale+50)/100, (spec.Height*scale+50)/100
+			fn := path.Join(dir, fmt.Sprintf("%s.scale-%d.png", spec.Name, scale))
+			log.Println("generating", fn)
+			if spec.Compose {
+				if err = BackgroundForegroundHandler(bm, fm, fn, &launchImageSpec{Width: w, Height: h}); err != nil {
+					return err
+				}
+				continue
+			}
+			length := int(float64(h) * spec.Ratio)
+			im := util.Center(resize.Resize(uint(length), uint(length), m, resize.Bilinear), w, h)
+			if err = saveAndCrush(im, fn); err != nil {
+				log.Println(fn, err)
+				return err
+			}
+		}
+	}
+
+	for _, size := range msixTargetSizes {
+		length := int(float64(size)*msixPlatedLogoRatio + 0.5)
+		plated := util.Center(resize.Resize(uint(length), uint(length), m, resize.Bilinear), size, size)
+		unplated := resize.Resize(uint(size), uint(size), m, resize.Bilinear)
+		for _, variant := range []struct {
+			filename string
+			image    image.Image
+		}{
+			{fmt.Sprintf("Square44x44Logo.targetsize-%d.png", size), plated},
+			{fmt.Sprintf("Square44x44Logo.targetsize-%d_altform-unplated.png", size), unplated},
+		} {
+			fn := path.Join(dir, variant.filename)
+			if err = saveAndCrush(variant.image, fn); err != nil {
+				log.Println(fn, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadMSIXBackgroundForeground returns the background and the foreground of wide tiles and the splash screen,
+// the background stays transparent so the tile color of the manifest shows through unless one is given,
+// and the app icon is the foreground unless one is given.
+func loadMSIXBackgroundForeground(m image.Image) (bm image.Image, fm image.Image, err error) {
+	if backgroundImagePath == "" && backgroundColor == "" && backgroundGradient == "" {
+		bm = (&util.Fill{Kind: util.FillSolid, Colors: []color.NRGBA{{}}}).Image(fillPreviewLength, fillPreviewLength)
+	} else if bm, err = loadBackground(); err != nil {
+		return nil, nil, err
+	}
+
+	fm = m
+	if foregroundImagePath != "" {
+		if fm, err = loadForeground(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return bm, fm, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/missdeer/yairc/util"
+	"github.com/nfnt/resize"
+)
+
+type assetAppearance struct {
+	Appearance string `json:"appearance"`
+	Value      string `json:"value"`
+}
+
+type assetImage struct {
+	Appearances []assetAppearance `json:"appearances,omitempty"`
+	Filename    string            `json:"filename,omitempty"`
+	Idiom       string            `json:"idiom"`
+	Platform    string            `json:"platform,omitempty"`
+	Role        string            `json:"role,omitempty"`
+	Scale       string            `json:"scale,omitempty"`
+	Size        string            `json:"size,omitempty"`
+	Subtype     string            `json:"subtype,omitempty"`
+}
+
+type assetColorComponents struct {
+	Alpha string `json:"alpha"`
+	Blue  string `json:"blue"`
+	Green string `json:"green"`
+	Red   string `json:"red"`
+}
+
+type assetColorValue struct {
+	ColorSpace string               `json:"color-space"`
+	Components assetColorComponents `json:"components"`
+}
+
+type assetColor struct {
+	Color assetColorValue `json:"color"`
+	Idiom string          `json:"idiom"`
+}
+
+type assetInfo struct {
+	Author  string `json:"author"`
+	Version int    `json:"version"`
+}
+
+type assetLayer struct {
+	Filename string `json:"filename"`
+}
+
+type assetContents struct {
+	Assets     []assetImage           `json:"assets,omitempty"`
+	Colors     []assetColor           `json:"colors,omitempty"`
+	Images     []assetImage           `json:"images,omitempty"`
+	Info       assetInfo              `json:"info"`
+	Layers     []assetLayer           `json:"layers,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+var xcodeAssetInfo = assetInfo{Author: "xcode", Version: 1}
+
+// writeContentsJson marshals the asset catalog description v into dir/Contents.json.
+func writeContentsJson(dir string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "Contents.json"), append(content, '\n'), 0644)
+}
+
+// writeImageSet writes m at 1x/2x/3x into dir/name.imageset, the 1x image being width*height pixels.
+func writeImageSet(m image.Image, dir string, name string, width, height int) error {
+	return writeImageSetScales(m, dir, name, width, height, "universal", []int{1, 2, 3})
+}
+
+// writeImageSetScales writes m at the given scales for idiom into dir/name.imageset.
+func writeImageSetScales(m image.Image, dir string, name string, width, height int, idiom string, scales []int) error {
+	dir = filepath.Join(dir, name+".imageset")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	contents := assetContents{Info: xcodeAssetInfo}
+	for _, scale := range scales {
+		filename := name + ".png"
+		if scale > 1 {
+			filename = fmt.Sprintf("%s@%dx.png", name, scale)
+		}
+		fn := filepath.Join(dir, filename)
+		im := m
+		if fi, ok := m.(*util.FillImage); ok {
+			im = fi.Fill.Render(width*scale, height*scale)
+		} else if sz := m.Bounds().Size(); sz.X != width*scale || sz.Y != height*scale {
+			im = resize.Resize(uint(width*scale), uint(height*scale), m, resize.Bilinear)
+		}
+		save := saveAndCrush
+		if isGradient(m) {
+			save = func(m image.Image, fn string) error { return util.SaveImage(m, fn, util.IT_png) }
+		}
+		if err := save(im, fn); err != nil {
+			log.Println(fn, err)
+			return err
+		}
+		contents.Images = append(contents.Images, assetImage{
+			Filename: filename,
+			Idiom:    idiom,
+			Scale:    fmt.Sprintf("%dx", scale),
+		})
+	}
+	return writeContentsJson(dir, contents)
+}
+
+// writeColorSet writes c as the universal sRGB color of dir/name.colorset.
+func writeColorSet(c color.Color, dir string, name string) error {
+	dir = filepath.Join(dir, name+".colorset")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return writeContentsJson(dir, assetContents{
+		Colors: []assetColor{{
+			Color: assetColorValue{
+				ColorSpace: "srgb",
+				Components: assetColorComponents{
+					Alpha: fmt.Sprintf("%.3f", float64(n.A)/255),
+					Blue:  fmt.Sprintf("0x%02X", n.B),
+					Green: fmt.Sprintf("0x%02X", n.G),
+					Red:   fmt.Sprintf("0x%02X", n.R),
+				},
+			},
+			Idiom: "universal",
+		}},
+		Info: xcodeAssetInfo,
+	})
+}
+
+// loadLayerImages decodes the layer images ordered from front to back, falling back to the foreground and background images.
+func loadLayerImages() (names []string, layers []image.Image, err error) {
+	paths := layerImagePaths
+	if len(paths) == 0 && foregroundImagePath != "" && backgroundImagePath != "" {
+		paths = []string{foregroundImagePath, backgroundImagePath}
+	}
+	switch len(paths) {
+	case 2:
+		names = []string{"Front", "Back"}
+	case 3:
+		names = []string{"Front", "Middle", "Back"}
+	default:
+		return nil, nil, errors.New("2 or 3 layer images are required, ordered from front to back")
+	}
+
+	for _, p := range paths {
+		reader, err := util.OpenURI(p)
+		if err != nil {
+			log.Println(p, err)
+			return nil, nil, err
+		}
+		m, _, err := util.ImageDecode(reader)
+		reader.Close()
+		if err != nil {
+			log.Println(p, err)
+			return nil, nil, err
+		}
+		layers = append(layers, m)
+	}
+	return names, layers, nil
+}
+
+// renderLayer scales a layer to width*height. The back layer covers the whole area and must be opaque,
+// the other layers are fitted on a transparent canvas so that nothing is cropped.
+func renderLayer(m image.Image, width, height int, back bool) image.Image {
+	if back {
+		return util.Cover(m, width, height)
+	}
+	return util.Center(util.Fit(m, width, height), width, height)
+}
+
+// writeImageStack writes a layered image stack directory, ext being imagestack or solidimagestack.
+func writeImageStack(names []string, layers []image.Image, dir string, ext string, width, height int, idiom string, scales []int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	contents := assetContents{Info: xcodeAssetInfo}
+	for i, name := range names {
+		layerDir := filepath.Join(dir, name+"."+ext+"layer")
+		if err := os.MkdirAll(layerDir, 0755); err != nil {
+			return err
+		}
+		maxScale := scales[len(scales)-1]
+		m := renderLayer(layers[i], width*maxScale, height*maxScale, i == len(names)-1)
+		if err := writeImageSetScales(m, layerDir, "Content", width, height, idiom, scales); err != nil {
+			return err
+		}
+		if err := writeContentsJson(layerDir, assetContents{Info: xcodeAssetInfo}); err != nil {
+			return err
+		}
+		contents.Layers = append(contents.Layers, assetLayer{Filename: name + "." + ext + "layer"})
+	}
+	return writeContentsJson(dir, contents)
+}